@@ -0,0 +1,173 @@
+package gonx
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// DefaultForwardedField is the nginx variable with the X-Forwarded-For header.
+const DefaultForwardedField = "http_x_forwarded_for"
+
+// TrustedProxies resolves the client IP address of requests passed through
+// trusted reverse proxies and load balancers.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// NewTrustedProxies creates a resolver trusting given networks, e.g.
+// "10.0.0.0/8" or a single address "192.168.1.1".
+func NewTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	p := &TrustedProxies{}
+	for _, cidr := range cidrs {
+		prefix, err := parseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		p.prefixes = append(p.prefixes, prefix)
+	}
+	return p, nil
+}
+
+// Trusted reports whether the address belongs to a trusted proxy.
+func (p *TrustedProxies) Trusted(addr netip.Addr) bool {
+	if p == nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address for the remote address and the value of
+// X-Forwarded-For header. The header is honored only if the remote address is
+// trusted, then the rightmost untrusted address of it is the client one. An
+// error is returned if the remote address is invalid or a hop to be walked is
+// malformed, as the last trusted proxy is not the client.
+func (p *TrustedProxies) ClientIP(remoteAddr, forwardedFor string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(remoteAddr))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	addr = addr.Unmap()
+	if !p.Trusted(addr) || forwardedFor == "" || forwardedFor == "-" {
+		return addr, nil
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := parseHop(hops[i])
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("X-Forwarded-For %q: %w", forwardedFor, err)
+		}
+		addr = hop.Unmap()
+		if !p.Trusted(addr) {
+			break
+		}
+	}
+	return addr, nil
+}
+
+// parseHop parses an X-Forwarded-For address which may include a port.
+func parseHop(hop string) (netip.Addr, error) {
+	hop = strings.TrimSpace(hop)
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		addrPort, perr := netip.ParseAddrPort(hop)
+		if perr != nil {
+			return addr, err
+		}
+		addr = addrPort.Addr()
+	}
+	return addr, nil
+}
+
+// Default anonymization prefix lengths, the last octet of IPv4 and all but /48
+// network of IPv6 addresses are zeroed.
+const (
+	DefaultAnonymizeIPv4Bits = 24
+	DefaultAnonymizeIPv6Bits = 48
+)
+
+// AnonymizeIP zeroes the host part of the address keeping the given number of
+// leading bits for IPv4 and IPv6 addresses.
+func AnonymizeIP(addr netip.Addr, ipv4Bits, ipv6Bits int) netip.Addr {
+	bits := ipv6Bits
+	if addr.Is4() {
+		bits = ipv4Bits
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return addr
+	}
+	return prefix.Addr()
+}
+
+// DefaultClientIPField is the field ClientIP mapper sets if no Target is given.
+const DefaultClientIPField = "client_ip"
+
+// ClientIP implements the Mapper interface to compute the client address of
+// requests passed through trusted proxies. The ForwardedField (X-Forwarded-For
+// header) is walked right-to-left skipping Proxies, see TrustedProxies.ClientIP.
+//
+// The address is stored in Target field. With Anonymize set the address is
+// truncated to IPv4Bits and IPv6Bits prefix, by default to /24 and /48. Entries
+// with invalid address or forwarded header get an empty Target field, the error
+// is reported to ErrorHandler.
+type ClientIP struct {
+	Field          string
+	ForwardedField string
+	Target         string
+	Proxies        *TrustedProxies
+	Anonymize      bool
+	IPv4Bits       int
+	IPv6Bits       int
+}
+
+// NewClientIP creates a ClientIP mapper setting client_ip field from remote_addr
+// and http_x_forwarded_for ones, trusting given proxy networks.
+func NewClientIP(trusted ...string) (*ClientIP, error) {
+	proxies, err := NewTrustedProxies(trusted...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientIP{
+		Field:          DefaultRemoteAddrField,
+		ForwardedField: DefaultForwardedField,
+		Target:         DefaultClientIPField,
+		Proxies:        proxies,
+	}, nil
+}
+
+// Map sets the client address field.
+func (m *ClientIP) Map(entry *Entry) *Entry {
+	field := m.Target
+	if field == "" {
+		field = DefaultClientIPField
+	}
+	addr, ok := clientAddr(entry, m.Field, m.ForwardedField, m.Proxies)
+	if !ok {
+		entry.SetField(field, "")
+		return entry
+	}
+	if m.Anonymize {
+		addr = AnonymizeIP(addr, orDefault(m.IPv4Bits, DefaultAnonymizeIPv4Bits), orDefault(m.IPv6Bits, DefaultAnonymizeIPv6Bits))
+	}
+	entry.SetField(field, addr.String())
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *ClientIP) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+func orDefault(value, def int) int {
+	if value <= 0 {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,71 @@
+package gonx
+
+import (
+	"net/netip"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClientIP(t *testing.T) {
+	Convey("Test client IP resolution", t, func() {
+		m, err := NewClientIP("10.0.0.0/8", "fd00::/8")
+		So(err, ShouldBeNil)
+
+		client := func(fields Fields) string {
+			entry := m.Map(NewEntry(fields))
+			val, _ := entry.StringField("client_ip")
+			return val
+		}
+
+		So(client(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "198.51.100.7, 10.1.2.3"}), ShouldEqual, "198.51.100.7")
+		So(client(Fields{"remote_addr": "fd00::1", "http_x_forwarded_for": "2001:db8::7"}), ShouldEqual, "2001:db8::7")
+		// Ports are allowed in the header
+		So(client(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "198.51.100.7:4711, [2001:db8::1]:443"}), ShouldEqual, "2001:db8::1")
+		So(client(Fields{"remote_addr": "203.0.113.9", "http_x_forwarded_for": "198.51.100.7"}), ShouldEqual, "203.0.113.9")
+		So(client(Fields{"remote_addr": "10.0.0.1"}), ShouldEqual, "10.0.0.1")
+		So(client(Fields{"remote_addr": "not an ip"}), ShouldEqual, "")
+
+		Convey("Malformed forwarded header", func() {
+			var errs []error
+			ErrorHandler = func(err error) { errs = append(errs, err) }
+			defer func() { ErrorHandler = nil }()
+
+			So(client(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "198.51.100.7, bogus, 10.1.2.3"}), ShouldEqual, "")
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Error(), ShouldContainSubstring, "bogus")
+		})
+
+		Convey("Anonymize", func() {
+			m.Anonymize = true
+			So(client(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "198.51.100.7"}), ShouldEqual, "198.51.100.0")
+			So(client(Fields{"remote_addr": "2001:db8:1:2:3::4"}), ShouldEqual, "2001:db8:1::")
+
+			m.IPv4Bits, m.IPv6Bits = 16, 32
+			So(client(Fields{"remote_addr": "198.51.100.7"}), ShouldEqual, "198.51.0.0")
+			So(client(Fields{"remote_addr": "2001:db8:1:2:3::4"}), ShouldEqual, "2001:db8::")
+		})
+
+		Convey("Aggregate per client", func() {
+			input := make(chan *Entry, 3)
+			output := make(chan *Entry, 2)
+			input <- NewEntry(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "198.51.100.7"})
+			input <- NewEntry(Fields{"remote_addr": "10.0.0.2", "http_x_forwarded_for": "198.51.100.8"})
+			input <- NewEntry(Fields{"remote_addr": "10.0.0.1", "http_x_forwarded_for": "203.0.113.1"})
+			close(input)
+
+			m.Anonymize = true
+			NewChain(m, NewGroupBy([]string{"client_ip"}, &Count{})).Reduce(input, output)
+			first := <-output
+			ip, _ := first.StringField("client_ip")
+			count, _ := first.FloatField("count")
+			So(ip, ShouldEqual, "198.51.100.0")
+			So(count, ShouldEqual, 2)
+		})
+	})
+
+	Convey("Test AnonymizeIP", t, func() {
+		So(AnonymizeIP(netip.MustParseAddr("192.0.2.255"), 24, 48).String(), ShouldEqual, "192.0.2.0")
+		So(AnonymizeIP(netip.MustParseAddr("fe80::1%eth0"), 24, 64).String(), ShouldEqual, "fe80::")
+	})
+}
@@ -0,0 +1,226 @@
+package gonx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a log stream compression format.
+type Compression int
+
+// Compression formats detected by magic bytes.
+const (
+	NoCompression Compression = iota
+	Gzip
+	Bzip2
+	Zstd
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// magicSize is the number of leading bytes enough to detect compression.
+const magicSize = 4
+
+// readaheadSize is the size of data chunks decompressed ahead.
+const readaheadSize = 256 * 1024
+
+// DetectCompression returns the compression format of the stream with given
+// leading bytes.
+func DetectCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return Zstd
+	case len(header) >= 4 && bytes.HasPrefix(header, bzip2Magic) && header[3] >= '1' && header[3] <= '9':
+		return Bzip2
+	}
+	return NoCompression
+}
+
+// Decompressor detects a log stream compression by magic bytes and decompresses
+// it transparently. Gzip streams of multiple members, as produced by appending
+// to a compressed log, are read as one.
+//
+// Concurrency is the number of goroutines used to decompress a stream, all
+// available CPUs are used if it is zero. Zstd frames are decoded concurrently,
+// gzip and bzip2 streams are decompressed ahead in a separate goroutine, so
+// decompression runs in parallel with parsing.
+type Decompressor struct {
+	Concurrency int
+}
+
+// Decompress returns the reader of decompressed data using default Decompressor.
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	return Decompressor{}.Reader(r)
+}
+
+// Open opens the named log file for reading using default Decompressor.
+func Open(name string) (io.ReadCloser, error) {
+	return Decompressor{}.Open(name)
+}
+
+func (d Decompressor) concurrency() int {
+	if d.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return d.Concurrency
+}
+
+// Reader returns the reader of decompressed data of r. Closing it does not
+// close r.
+func (d Decompressor) Reader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(magicSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var decompressed io.ReadCloser
+	switch DetectCompression(header) {
+	case Gzip:
+		decompressed, err = gzip.NewReader(buffered)
+	case Bzip2:
+		decompressed = io.NopCloser(bzip2.NewReader(buffered))
+	case Zstd:
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(buffered, zstd.WithDecoderConcurrency(d.concurrency()))
+		if err == nil {
+			decompressed = decoder.IOReadCloser()
+		}
+		return decompressed, err
+	default:
+		return io.NopCloser(buffered), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.concurrency() > 1 {
+		decompressed = newReadahead(decompressed, d.concurrency())
+	}
+	return decompressed, nil
+}
+
+// Open opens the named log file for reading, decompressing it if needed.
+func (d Decompressor) Open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := d.Reader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileReader{ReadCloser: reader, file: file}, nil
+}
+
+// fileReader closes both decompressed stream and the underlying file.
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadCloser.Close()
+	if ferr := r.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// readahead reads source in a separate goroutine and keeps up to n chunks of
+// data ahead of the consumer.
+type readahead struct {
+	src    io.ReadCloser
+	chunks chan []byte
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	chunk  []byte
+	err    error
+	srcErr error
+}
+
+func newReadahead(src io.ReadCloser, n int) *readahead {
+	r := &readahead{
+		src:    src,
+		chunks: make(chan []byte, n),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *readahead) run() {
+	defer close(r.done)
+	defer close(r.chunks)
+	for {
+		buf := make([]byte, readaheadSize)
+		n, err := fill(r.src, buf)
+		if n > 0 {
+			select {
+			case r.chunks <- buf[:n]:
+			case <-r.stop:
+				r.srcErr = io.ErrClosedPipe
+				return
+			}
+		}
+		if err != nil {
+			// Safe to set, it is read after chunks channel is closed
+			r.srcErr = err
+			return
+		}
+	}
+}
+
+// fill reads into buf until it is full or an error occurs. Unlike io.ReadFull,
+// it returns the source error as is, so a truncated stream reported by the
+// decoder as io.ErrUnexpectedEOF is not taken for the end of data.
+func fill(src io.Reader, buf []byte) (int, error) {
+	var n int
+	for n < len(buf) {
+		m, err := src.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (r *readahead) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		chunk, ok := <-r.chunks
+		if !ok {
+			r.err = r.srcErr
+			continue
+		}
+		r.chunk = chunk
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+func (r *readahead) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+	return r.src.Close()
+}
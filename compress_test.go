@@ -0,0 +1,107 @@
+package gonx
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func gzipMembers(members ...string) []byte {
+	var buf bytes.Buffer
+	for _, member := range members {
+		w := gzip.NewWriter(&buf)
+		w.Write([]byte(member))
+		w.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	Convey("Test transparent decompression", t, func() {
+		readAll := func(d Decompressor, data []byte) string {
+			reader, err := d.Reader(bytes.NewReader(data))
+			So(err, ShouldBeNil)
+			defer reader.Close()
+			result, err := io.ReadAll(reader)
+			So(err, ShouldBeNil)
+			return string(result)
+		}
+
+		Convey("Detect compression", func() {
+			So(DetectCompression([]byte{0x1f, 0x8b, 8, 0}), ShouldEqual, Gzip)
+			So(DetectCompression([]byte("BZh9")), ShouldEqual, Bzip2)
+			So(DetectCompression([]byte{0x28, 0xb5, 0x2f, 0xfd}), ShouldEqual, Zstd)
+			So(DetectCompression([]byte("BZh ")), ShouldEqual, NoCompression)
+			So(DetectCompression([]byte("1.2.3.4 - -")), ShouldEqual, NoCompression)
+		})
+
+		Convey("Plain text", func() {
+			So(readAll(Decompressor{}, []byte("foo\nbar\n")), ShouldEqual, "foo\nbar\n")
+			So(readAll(Decompressor{}, []byte("a")), ShouldEqual, "a")
+		})
+
+		Convey("Multi-member gzip", func() {
+			data := gzipMembers("foo\n", "bar\n")
+			So(readAll(Decompressor{Concurrency: 1}, data), ShouldEqual, "foo\nbar\n")
+			So(readAll(Decompressor{Concurrency: 4}, data), ShouldEqual, "foo\nbar\n")
+		})
+
+		Convey("Large gzip read ahead", func() {
+			text := strings.Repeat("89.234.89.123 [08/Nov/2013:13:39:18 +0000] \"GET / HTTP/1.1\"\n", 20000)
+			So(readAll(Decompressor{Concurrency: 2}, gzipMembers(text)), ShouldEqual, text)
+		})
+
+		Convey("Truncated gzip", func() {
+			text := strings.Repeat("89.234.89.123 [08/Nov/2013:13:39:18 +0000] \"GET / HTTP/1.1\"\n", 20000)
+			data := gzipMembers(text)
+			data = data[:len(data)/2]
+			for _, concurrency := range []int{1, 4} {
+				reader, err := Decompressor{Concurrency: concurrency}.Reader(bytes.NewReader(data))
+				So(err, ShouldBeNil)
+				_, err = io.ReadAll(reader)
+				So(err, ShouldEqual, io.ErrUnexpectedEOF)
+				reader.Close()
+			}
+		})
+
+		Convey("Zstd", func() {
+			var buf bytes.Buffer
+			w, err := zstd.NewWriter(&buf)
+			So(err, ShouldBeNil)
+			w.Write([]byte("foo\nbar\n"))
+			w.Close()
+			So(readAll(Decompressor{}, buf.Bytes()), ShouldEqual, "foo\nbar\n")
+		})
+
+		Convey("Read compressed rotated logs", func() {
+			dir := t.TempDir()
+			So(os.WriteFile(filepath.Join(dir, "access.log"), []byte("3\n"), 0644), ShouldBeNil)
+			So(os.WriteFile(filepath.Join(dir, "access.log.1.gz"), gzipMembers("2\n"), 0644), ShouldBeNil)
+			So(os.WriteFile(filepath.Join(dir, "access.log.2.gz"), gzipMembers("1\n"), 0644), ShouldBeNil)
+
+			input, err := NewGlob(filepath.Join(dir, "access.log*"))
+			So(err, ShouldBeNil)
+			output := MapReduceInput(input, NewParser("$id"), &Sum{map[string]string{"id": "id"}})
+
+			result := <-output
+			value, err := result.FloatField("id")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 1+2+3)
+		})
+
+		Convey("Reader of gzip stream", func() {
+			reader := NewReader(bytes.NewReader(gzipMembers("1 a\n")), "$id $name")
+			entry, err := reader.Read()
+			So(err, ShouldBeNil)
+			name, _ := entry.StringField("name")
+			So(name, ShouldEqual, "a")
+		})
+	})
+}
@@ -13,8 +13,36 @@ type Types map[string]string
 
 // Entry is a parsed log record. Use Get method to retrieve a value by name instead of
 // threating this as a map, because inner representation is in design.
+//
+// Position is the location of the parsed line if the input is configured to
+// keep it. It is not a part of Fields, so it is neither seen by reducers nor
+// marshaled to JSON.
 type Entry struct {
-	Fields Fields
+	Fields   Fields
+	Position *Position
+}
+
+// Position is the location of the log line an Entry was parsed from.
+type Position struct {
+	// Source is the file name, empty if unknown.
+	Source string
+	// Line is the line number starting from 1, zero if unknown, e.g. for
+	// followed files.
+	Line int64
+	// Offset is the byte offset of the line start, in the decompressed data
+	// for compressed files.
+	Offset int64
+	// Raw is the line text as it was parsed.
+	Raw string
+}
+
+// String returns the position as `source:line` or `source@offset` if the line
+// number is unknown.
+func (p Position) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%v:%d", p.Source, p.Line)
+	}
+	return fmt.Sprintf("%v@%d", p.Source, p.Offset)
 }
 
 // NewEmptyEntry creates an empty Entry to be filled later
@@ -27,6 +55,16 @@ func NewEntry(fields Fields) *Entry {
 	return &Entry{Fields: fields}
 }
 
+// copy returns a shallow copy of the entry, so its fields could be set without
+// affecting the original.
+func (entry *Entry) copy() *Entry {
+	fields := make(Fields, len(entry.Fields))
+	for name, value := range entry.Fields {
+		fields[name] = value
+	}
+	return &Entry{Fields: fields, Position: entry.Position}
+}
+
 // Field returns an entry field value by name or empty string and error if it
 // does not exist.
 func (entry *Entry) Field(name string) (value any, err error) {
@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/dreamsxin/gonx"
@@ -24,71 +23,42 @@ func init() {
 	flag.StringVar(&logFile, "log", "access.log", "Log file name")
 }
 
-var (
-	locker sync.Mutex
-	logs   []string
-)
-
 func main() {
+	flag.Parse()
 
 	// Create a parser based on given format
 	parser := gonx.NewParser(format)
 
-	t, err := follower.New(logFile, follower.Config{
-		Whence: io.SeekStart,
-		Offset: 0,
-		Reopen: true,
+	reader, err := gonx.NewFollowReader(logFile, parser, gonx.FollowConfig{
+		Config: follower.Config{
+			Whence: io.SeekStart,
+			Offset: 0,
+			Reopen: true,
+		},
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	reducer := gonx.NewChain(
+	go func() {
+		for err := range reader.Errors() {
+			log.Println(err)
+		}
+	}()
+
+	// Daily statistics, the window of the day is reported when the next day starts
+	window := gonx.NewWindow("time_local", 24*time.Hour, gonx.NewChain(
 		&gonx.Avg{Fields: map[string]string{"request_time": "request_time", "read_time": "read_time", "gen_time": "gen_time"}},
 		&gonx.Sum{Fields: map[string]string{"body_bytes_sent": "body_bytes_sent"}},
 		&gonx.Count{},
-	)
-	var wg sync.WaitGroup
-	var entries chan *gonx.Entry
-	var lasttime time.Time
-	for line := range t.Lines() {
-		//log.Println(line.String())
-		entry, err := parser.ParseString(line.String())
-		if err == nil {
-			time_local, err := entry.StringField("time_local")
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			t, err := time.ParseInLocation("02/Jan/2006:15:04:05 -0700", time_local, time.Local)
-			if err != nil {
-				log.Println(err)
-				continue
-			}
-			if lasttime.Format("2006-01-02") != t.Format("2006-01-02") {
-				if entries != nil {
-					close(entries)
-				}
+	))
+	window.Format = gonx.TimeLocalLayout
 
-				log.Println("start new day", t.Format("2006-01-02"))
-				entries = make(chan *gonx.Entry, 10)
-				wg.Add(1)
-				go func(t time.Time, entries chan *gonx.Entry) {
-					defer wg.Done()
-					var output = make(chan *gonx.Entry)
-					go reducer.Reduce(entries, output)
-					for res := range output {
-						// Process the record... e.g.
-						fmt.Printf("Parsed entry: %s, %+v\n", t.Format("2006-01-02"), res)
-					}
-
-				}(t, entries)
-			}
-			lasttime = t
-			entries <- entry
-		} else {
-			log.Println(err)
-		}
+	for res := range reader.Reduce(window) {
+		// Process the record... e.g.
+		fmt.Printf("Parsed entry: %+v\n", res)
+	}
+	if err := reader.Err(); err != nil {
+		log.Println(err)
 	}
-	wg.Wait()
 }
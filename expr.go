@@ -0,0 +1,621 @@
+package gonx
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expr implements the Filter interface to filter Entries matching an expression.
+//
+// Expression consists of field comparisons joined with boolean operators `&&`,
+// `||`, `!` (or `and`, `or`, `not`) and parentheses, e.g.
+//
+//	status >= 500 && request_time > 1.5 && request_uri =~ "^/api/"
+//
+// A comparison is a field name (optionally prefixed with `$`) followed by an
+// operator and a literal:
+//
+//   - `==`, `!=`, `<`, `<=`, `>`, `>=` compare a field value with a number
+//     (`500`, `1.5`), a string (`"GET"` or `'GET'`) or a time literal
+//     (`time("2024-01-02T03:04:05Z")`), using the literal type;
+//   - `=~` and `!~` match a field value with a regular expression string;
+//   - `in` and `not in` check a field value to be one of the list literals,
+//     e.g. `status in [500, 502, 504]`;
+//   - CIDR literal `cidr("10.0.0.0/8")` matches IP addresses within the range
+//     with `==`, `!=`, `in` and `not in`.
+//
+// Comparisons with a missing field or a value of wrong type are false.
+type Expr struct {
+	Source string
+	root   exprNode
+}
+
+// ExprError is an expression compilation error with the position in the source.
+type ExprError struct {
+	Source string
+	Pos    int
+	Msg    string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("expression '%v' at position %d: %v", e.Source, e.Pos, e.Msg)
+}
+
+// CompileExpr compiles the expression source into a Filter.
+func CompileExpr(source string) (*Expr, error) {
+	p := &exprParser{lexer: exprLexer{source: source}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %v", p.tok)
+	}
+	return &Expr{Source: source, root: root}, nil
+}
+
+// MustCompileExpr is like CompileExpr but panics if the expression cannot be
+// compiled.
+func MustCompileExpr(source string) *Expr {
+	expr, err := CompileExpr(source)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+// Match reports whether the entry matches the expression.
+func (e *Expr) Match(entry *Entry) bool {
+	return e.root.eval(entry)
+}
+
+// Filter implements the Filter interface.
+func (e *Expr) Filter(entry *Entry) *Entry {
+	if e.Match(entry) {
+		return entry
+	}
+	return nil
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (e *Expr) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(e, input, output)
+}
+
+func (e *Expr) String() string {
+	return e.Source
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%v'", t.text)
+}
+
+type exprLexer struct {
+	source string
+	pos    int
+}
+
+func (l *exprLexer) errorf(pos int, format string, args ...any) error {
+	return &ExprError{Source: l.source, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+var exprPunctuation = map[byte]tokenKind{'(': tokLParen, ')': tokRParen, '[': tokLBracket, ']': tokRBracket, ',': tokComma}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+func (l *exprLexer) next() (token, error) {
+	for l.pos < len(l.source) && unicode.IsSpace(rune(l.source[l.pos])) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.source) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+	c := l.source[l.pos]
+	switch {
+	case c == '(' || c == ')' || c == '[' || c == ']' || c == ',':
+		l.pos++
+		return token{kind: exprPunctuation[c], text: string(c), pos: start}, nil
+	case c == '"' || c == '\'':
+		return l.lexString(c)
+	case c >= '0' && c <= '9' || c == '-' || c == '.':
+		l.pos++
+		for l.pos < len(l.source) && strings.IndexByte("0123456789.eE+-", l.source[l.pos]) >= 0 {
+			// Sign is a part of the number only after exponent
+			if s := l.source[l.pos]; (s == '+' || s == '-') && !strings.ContainsRune("eE", rune(l.source[l.pos-1])) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.source[start:l.pos], pos: start}, nil
+	case c == '$' || c == '_' || unicode.IsLetter(rune(c)):
+		l.pos++
+		for l.pos < len(l.source) {
+			c := rune(l.source[l.pos])
+			if c != '_' && c != '.' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.source[start:l.pos], pos: start}, nil
+	}
+	for _, op := range exprOperators {
+		if strings.HasPrefix(l.source[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, l.errorf(start, "unexpected character '%c'", c)
+}
+
+func (l *exprLexer) lexString(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var value strings.Builder
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tokString, text: value.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.source):
+			// Keep escaped backslashes for regular expressions, unescape quotes only
+			if next := l.source[l.pos+1]; next == quote || next == '\\' {
+				value.WriteByte(next)
+			} else {
+				value.WriteByte(c)
+				value.WriteByte(next)
+			}
+			l.pos += 2
+		default:
+			value.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+type exprParser struct {
+	lexer exprLexer
+	tok   token
+}
+
+func (p *exprParser) next() (err error) {
+	p.tok, err = p.lexer.next()
+	return
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return p.lexer.errorf(p.tok.pos, format, args...)
+}
+
+// isOp reports whether the current token is one of given operators or keywords.
+func (p *exprParser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp && p.tok.kind != tokIdent {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||", "or") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&", "and") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.isOp("!", "not") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node}, nil
+	}
+	if p.tok.kind == tokLParen {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ')', got %v", p.tok)
+		}
+		return node, p.next()
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	if p.tok.kind != tokIdent || isExprKeyword(p.tok.text) {
+		return nil, p.errorf("expected field name, got %v", p.tok)
+	}
+	field := strings.TrimPrefix(p.tok.text, "$")
+	if field == "" {
+		return nil, p.errorf("empty field name")
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	op := p.tok
+	switch {
+	case p.isOp("in"):
+		return p.parseIn(field, false)
+	case p.isOp("not"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !p.isOp("in") {
+			return nil, p.errorf("expected 'in' after 'not', got %v", p.tok)
+		}
+		return p.parseIn(field, true)
+	case p.isOp("=~", "!~"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokString {
+			return nil, p.errorf("expected regular expression string, got %v", p.tok)
+		}
+		re, err := regexp.Compile(p.tok.text)
+		if err != nil {
+			return nil, p.errorf("invalid regular expression: %v", err)
+		}
+		return &matchNode{field: field, re: re, negate: op.text == "!~"}, p.next()
+	case p.isOp("==", "!=", "<", "<=", ">", ">="):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if lit.kind == litCIDR && op.text != "==" && op.text != "!=" {
+			return nil, p.lexer.errorf(op.pos, "operator '%v' is not supported for CIDR", op.text)
+		}
+		return &compareNode{field: field, op: op.text, lit: lit}, nil
+	}
+	return nil, p.errorf("expected comparison operator after field '%v', got %v", field, p.tok)
+}
+
+func (p *exprParser) parseIn(field string, negate bool) (exprNode, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	node := &inNode{field: field, negate: negate}
+	if p.tok.kind != tokLBracket {
+		// Single CIDR literal is allowed without brackets
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		if lit.kind != litCIDR {
+			return nil, p.errorf("expected '[' or CIDR after 'in'")
+		}
+		node.values = append(node.values, lit)
+		return node, nil
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for p.tok.kind != tokRBracket {
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		node.values = append(node.values, lit)
+		if p.tok.kind == tokComma {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		} else if p.tok.kind != tokRBracket {
+			return nil, p.errorf("expected ',' or ']', got %v", p.tok)
+		}
+	}
+	if len(node.values) == 0 {
+		return nil, p.errorf("empty list")
+	}
+	return node, p.next()
+}
+
+func (p *exprParser) parseLiteral() (literal, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		num, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return literal{}, p.errorf("invalid number '%v'", tok.text)
+		}
+		return literal{kind: litNumber, num: num, str: tok.text}, p.next()
+	case tokString:
+		return literal{kind: litString, str: tok.text}, p.next()
+	case tokIdent:
+		if tok.text != "time" && tok.text != "cidr" {
+			return literal{}, p.errorf("expected literal, got %v", tok)
+		}
+		if err := p.next(); err != nil {
+			return literal{}, err
+		}
+		if p.tok.kind != tokLParen {
+			return literal{}, p.errorf("expected '(' after '%v'", tok.text)
+		}
+		if err := p.next(); err != nil {
+			return literal{}, err
+		}
+		arg := p.tok
+		if arg.kind != tokString {
+			return literal{}, p.errorf("expected string argument of '%v', got %v", tok.text, arg)
+		}
+		lit := literal{str: arg.text}
+		if tok.text == "time" {
+			t, err := ParseTime(arg.text)
+			if err != nil {
+				return literal{}, p.errorf("invalid time: %v", err)
+			}
+			lit.kind, lit.time = litTime, t
+		} else {
+			prefix, err := parseCIDR(arg.text)
+			if err != nil {
+				return literal{}, p.errorf("invalid CIDR: %v", err)
+			}
+			lit.kind, lit.prefix = litCIDR, prefix
+		}
+		if err := p.next(); err != nil {
+			return literal{}, err
+		}
+		if p.tok.kind != tokRParen {
+			return literal{}, p.errorf("expected ')', got %v", p.tok)
+		}
+		return lit, p.next()
+	}
+	return literal{}, p.errorf("expected literal, got %v", tok)
+}
+
+// parseCIDR parses a network prefix or a single IP address.
+func parseCIDR(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked(), nil
+}
+
+func isExprKeyword(s string) bool {
+	switch s {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
+
+type literalKind int
+
+const (
+	litNumber literalKind = iota
+	litString
+	litTime
+	litCIDR
+)
+
+type literal struct {
+	kind   literalKind
+	str    string
+	num    float64
+	time   time.Time
+	prefix netip.Prefix
+}
+
+// compare returns the result of comparison of the entry field value with the
+// literal and false if comparison is not possible.
+func (lit literal) compare(entry *Entry, field string) (int, bool) {
+	switch lit.kind {
+	case litNumber:
+		val, err := entry.FloatField(field)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case val < lit.num:
+			return -1, true
+		case val > lit.num:
+			return 1, true
+		}
+		return 0, true
+	case litString:
+		val, err := entry.StringField(field)
+		if err != nil {
+			return 0, false
+		}
+		return strings.Compare(val, lit.str), true
+	case litTime:
+		val, err := entry.StringField(field)
+		if err != nil {
+			return 0, false
+		}
+		t, err := ParseTime(val)
+		if err != nil {
+			return 0, false
+		}
+		return t.Compare(lit.time), true
+	case litCIDR:
+		val, err := entry.StringField(field)
+		if err != nil {
+			return 0, false
+		}
+		addr, err := netip.ParseAddr(strings.TrimSpace(val))
+		if err != nil {
+			return 0, false
+		}
+		if lit.prefix.Contains(addr.Unmap()) {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+type exprNode interface {
+	eval(entry *Entry) bool
+}
+
+type andNode struct {
+	left, right exprNode
+}
+
+func (n *andNode) eval(entry *Entry) bool {
+	return n.left.eval(entry) && n.right.eval(entry)
+}
+
+type orNode struct {
+	left, right exprNode
+}
+
+func (n *orNode) eval(entry *Entry) bool {
+	return n.left.eval(entry) || n.right.eval(entry)
+}
+
+type notNode struct {
+	node exprNode
+}
+
+func (n *notNode) eval(entry *Entry) bool {
+	return !n.node.eval(entry)
+}
+
+type compareNode struct {
+	field string
+	op    string
+	lit   literal
+}
+
+func (n *compareNode) eval(entry *Entry) bool {
+	c, ok := n.lit.compare(entry, n.field)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+type matchNode struct {
+	field  string
+	re     *regexp.Regexp
+	negate bool
+}
+
+func (n *matchNode) eval(entry *Entry) bool {
+	val, err := entry.StringField(n.field)
+	if err != nil {
+		return false
+	}
+	return n.re.MatchString(val) != n.negate
+}
+
+type inNode struct {
+	field  string
+	values []literal
+	negate bool
+}
+
+func (n *inNode) eval(entry *Entry) bool {
+	if _, err := entry.Field(n.field); err != nil {
+		return false
+	}
+	for _, lit := range n.values {
+		if c, ok := lit.compare(entry, n.field); ok && c == 0 {
+			return !n.negate
+		}
+	}
+	return n.negate
+}
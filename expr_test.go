@@ -0,0 +1,94 @@
+package gonx
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExpr(t *testing.T) {
+	Convey("Test expression filter", t, func() {
+		entry := NewEntry(Fields{
+			"status":       "502",
+			"request_time": "1.75",
+			"request_uri":  "/api/users/42",
+			"remote_addr":  "10.1.2.3",
+			"method":       "POST",
+			"time_local":   "08/Nov/2013:13:39:18 +0000",
+		})
+		match := func(source string) bool {
+			expr, err := CompileExpr(source)
+			So(err, ShouldBeNil)
+			return expr.Match(entry)
+		}
+
+		Convey("Compare numbers and strings", func() {
+			So(match(`status >= 500 && request_time > 1.5 && request_uri =~ "^/api/"`), ShouldBeTrue)
+			So(match(`$status == 502`), ShouldBeTrue)
+			So(match(`status < 500`), ShouldBeFalse)
+			So(match(`method == "POST"`), ShouldBeTrue)
+			So(match(`method != 'GET'`), ShouldBeTrue)
+			So(match(`request_uri !~ "^/api/"`), ShouldBeFalse)
+			So(match(`request_uri =~ "^/api/users/\d+$"`), ShouldBeTrue)
+		})
+
+		Convey("Boolean logic", func() {
+			So(match(`status < 500 || method == "POST"`), ShouldBeTrue)
+			So(match(`!(status < 500) and not method == "GET"`), ShouldBeTrue)
+			So(match(`status < 500 || method == "GET" && request_time > 1`), ShouldBeFalse)
+			So(match(`(status < 500 || method == "POST") && request_time > 1`), ShouldBeTrue)
+		})
+
+		Convey("Lists, CIDR and time", func() {
+			So(match(`status in [500, 502, 504]`), ShouldBeTrue)
+			So(match(`status not in [500, 504]`), ShouldBeTrue)
+			So(match(`method in ["GET", "HEAD"]`), ShouldBeFalse)
+			So(match(`remote_addr in cidr("10.0.0.0/8")`), ShouldBeTrue)
+			So(match(`remote_addr in [cidr("192.168.0.0/16"), cidr("10.1.2.3")]`), ShouldBeTrue)
+			So(match(`remote_addr == cidr("172.16.0.0/12")`), ShouldBeFalse)
+			So(match(`time_local >= time("2013-11-08T13:00:00Z") && time_local < time("2013-11-08 14:00:00")`), ShouldBeTrue)
+		})
+
+		Convey("Missing fields and wrong types", func() {
+			So(match(`missing == 1`), ShouldBeFalse)
+			So(match(`missing in [1, 2]`), ShouldBeFalse)
+			So(match(`missing not in [1, 2]`), ShouldBeFalse)
+			So(match(`method > 1`), ShouldBeFalse)
+		})
+
+		Convey("Compile errors", func() {
+			errorAt := func(source string) int {
+				_, err := CompileExpr(source)
+				So(err, ShouldNotBeNil)
+				exprErr, ok := err.(*ExprError)
+				So(ok, ShouldBeTrue)
+				return exprErr.Pos
+			}
+			So(errorAt(`status >=`), ShouldEqual, 9)
+			So(errorAt(`status 500`), ShouldEqual, 7)
+			So(errorAt(`(status > 1`), ShouldEqual, 11)
+			So(errorAt(`uri =~ "("`), ShouldEqual, 7)
+			So(errorAt(`uri == "foo`), ShouldEqual, 7)
+			So(errorAt(`ip < cidr("10.0.0.0/8")`), ShouldEqual, 3)
+			So(errorAt(`ip in cidr("10.0.0.0/33")`), ShouldEqual, 11)
+			So(errorAt(`status > 1 #`), ShouldEqual, 11)
+			So(func() { MustCompileExpr(`&&`) }, ShouldPanic)
+		})
+
+		Convey("Filter channel", func() {
+			input := make(chan *Entry, 2)
+			input <- entry
+			input <- NewEntry(Fields{"status": "200"})
+			close(input)
+			output := make(chan *Entry, 1)
+
+			chain := NewChain(MustCompileExpr(`status >= 500`), &Count{})
+			chain.Reduce(input, output)
+
+			result := <-output
+			count, err := result.FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+		})
+	})
+}
@@ -1,6 +1,9 @@
 package gonx
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Filter interface for Entries channel limiting.
 //
@@ -11,26 +14,45 @@ type Filter interface {
 	Filter(*Entry) *Entry
 }
 
+// BadTimePolicy defines how Datetime filter deals with entries without a valid
+// timestamp.
+type BadTimePolicy int
+
+const (
+	// DropBadTime silently drops entries with missing or invalid timestamp.
+	DropBadTime BadTimePolicy = iota
+	// KeepBadTime passes entries with missing or invalid timestamp.
+	KeepBadTime
+	// ReportBadTime drops entries with missing or invalid timestamp and reports
+	// the error to the ErrorHandler.
+	ReportBadTime
+)
+
 // Datetime implements the Filter interface to filter Entries with timestamp fields within
 // the specified datetime interval.
+//
+// Zero Start or End leaves the interval open. Start is inclusive, End is exclusive
+// unless IncludeEnd is set. If Format is empty, the timestamp layout is detected
+// by ParseTime. Timestamps without a timezone are parsed in Location, UTC if nil.
 type Datetime struct {
-	Field  string
-	Format string
-	Start  time.Time
-	End    time.Time
+	Field      string
+	Format     string
+	Start      time.Time
+	End        time.Time
+	IncludeEnd bool
+	Location   *time.Location
+	BadTime    BadTimePolicy
 }
 
 // Filter checks a field value to be in desired datetime range.
 func (i *Datetime) Filter(entry *Entry) (validEntry *Entry) {
 	val, err := entry.StringField(i.Field)
 	if err != nil {
-		// TODO handle error
-		return
+		return i.badTime(entry, err)
 	}
-	t, err := time.Parse(i.Format, val)
+	t, err := i.parse(val)
 	if err != nil {
-		// TODO handle error
-		return
+		return i.badTime(entry, fmt.Errorf("field '%v': %v", i.Field, err))
 	}
 	if i.withinBounds(t) {
 		validEntry = entry
@@ -48,14 +70,37 @@ func (i *Datetime) Reduce(input chan *Entry, output chan *Entry) {
 	close(output)
 }
 
+func (i *Datetime) parse(val string) (time.Time, error) {
+	loc := i.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	if i.Format == "" {
+		return ParseTimeInLocation(val, loc)
+	}
+	return time.ParseInLocation(i.Format, val, loc)
+}
+
+func (i *Datetime) badTime(entry *Entry, err error) *Entry {
+	switch i.BadTime {
+	case KeepBadTime:
+		return entry
+	case ReportBadTime:
+		handleError(err)
+	}
+	return nil
+}
+
 func (i *Datetime) withinBounds(t time.Time) bool {
-	if t.Equal(i.Start) {
-		return true
+	if !i.Start.IsZero() && t.Before(i.Start) {
+		return false
 	}
-	if t.After(i.Start) && t.Before(i.End) {
-		return true
+	if !i.End.IsZero() {
+		if t.After(i.End) || (t.Equal(i.End) && !i.IncludeEnd) {
+			return false
+		}
 	}
-	return false
+	return true
 }
 
 // FilterFunc implements the Filter interface to filter Entries with custom filter function.
@@ -77,3 +122,85 @@ func (f FilterFunc) Reduce(input chan *Entry, output chan *Entry) {
 	}
 	close(output)
 }
+
+// reduceFilter goes through input and writes to the output entries which meet
+// the filter condition.
+func reduceFilter(f Filter, input chan *Entry, output chan *Entry) {
+	for entry := range input {
+		if valid := f.Filter(entry); valid != nil {
+			output <- valid
+		}
+	}
+	close(output)
+}
+
+// And implements the Filter interface for entries which meet all of the filters
+// conditions.
+type And struct {
+	filters []Filter
+}
+
+// NewAnd creates a new And filter.
+func NewAnd(filters ...Filter) *And {
+	return &And{filters: filters}
+}
+
+// Filter checks an entry to meet all the filters in order.
+func (f *And) Filter(entry *Entry) *Entry {
+	return applyFilters(f.filters, entry)
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *And) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// Or implements the Filter interface for entries which meet any of the filters
+// conditions.
+type Or struct {
+	filters []Filter
+}
+
+// NewOr creates a new Or filter.
+func NewOr(filters ...Filter) *Or {
+	return &Or{filters: filters}
+}
+
+// Filter checks an entry to meet any of the filters in order.
+func (f *Or) Filter(entry *Entry) *Entry {
+	for _, filter := range f.filters {
+		if valid := filter.Filter(entry); valid != nil {
+			return valid
+		}
+	}
+	return nil
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Or) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// Not implements the Filter interface for entries which do not meet the filter
+// condition.
+type Not struct {
+	filter Filter
+}
+
+// NewNot creates a new Not filter.
+func NewNot(filter Filter) *Not {
+	return &Not{filter: filter}
+}
+
+// Filter checks an entry not to meet the filter.
+func (f *Not) Filter(entry *Entry) *Entry {
+	if f.filter.Filter(entry) != nil {
+		return nil
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Not) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
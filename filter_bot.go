@@ -0,0 +1,48 @@
+package gonx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultUserAgentField is the field used by Bot filter if none is configured.
+const DefaultUserAgentField = "http_user_agent"
+
+// BotRegexp matches user agents of common crawlers, monitoring services, HTTP
+// libraries and command line tools.
+var BotRegexp = regexp.MustCompile(`(?i)bot\b|bot/|crawl|spider|slurp|archiver|facebookexternalhit|` +
+	`mediapartners|feedfetcher|pingdom|uptime|monitor|headless|phantomjs|scrapy|` +
+	`curl/|wget/|python-requests|python-urllib|go-http-client|java/|libwww|okhttp|axios/|httpclient`)
+
+// Bot implements the Filter interface for entries with a user agent of a bot.
+// Empty user agents are treated as bots. Use NewNot to keep human traffic only.
+type Bot struct {
+	Field string
+}
+
+// IsBot reports whether the user agent belongs to a bot.
+func IsBot(userAgent string) bool {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" || userAgent == "-" {
+		return true
+	}
+	return BotRegexp.MatchString(userAgent)
+}
+
+// Filter checks the user agent field value to be a bot.
+func (f *Bot) Filter(entry *Entry) *Entry {
+	field := f.Field
+	if field == "" {
+		field = DefaultUserAgentField
+	}
+	val, err := entry.StringField(field)
+	if err != nil || !IsBot(val) {
+		return nil
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Bot) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
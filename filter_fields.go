@@ -0,0 +1,154 @@
+package gonx
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Equal implements the Filter interface for entries with the field equal to the
+// value.
+type Equal struct {
+	Field string
+	Value string
+}
+
+// Filter checks the field value to be equal to the Value.
+func (f *Equal) Filter(entry *Entry) *Entry {
+	val, err := entry.StringField(f.Field)
+	if err != nil || val != f.Value {
+		return nil
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Equal) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// Match implements the Filter interface for entries with the field matching the
+// regular expression.
+type Match struct {
+	Field  string
+	Regexp *regexp.Regexp
+}
+
+// Filter checks the field value to match the Regexp.
+func (f *Match) Filter(entry *Entry) *Entry {
+	val, err := entry.StringField(f.Field)
+	if err != nil || !f.Regexp.MatchString(val) {
+		return nil
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Match) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// Range implements the Filter interface for entries with the numeric field value
+// within [Min, Max] interval. Use math.Inf for an open interval.
+type Range struct {
+	Field string
+	Min   float64
+	Max   float64
+}
+
+// Filter checks the field value to be within the range.
+func (f *Range) Filter(entry *Entry) *Entry {
+	val, err := entry.FloatField(f.Field)
+	if err != nil || val < f.Min || val > f.Max {
+		return nil
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Range) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// CIDR implements the Filter interface for entries with the IP address field
+// within any of the network prefixes.
+type CIDR struct {
+	Field    string
+	Prefixes []netip.Prefix
+}
+
+// NewCIDR creates a CIDR filter for the field with given networks, e.g.
+// "10.0.0.0/8" or "2001:db8::/32". A single IP address is also accepted.
+func NewCIDR(field string, cidrs ...string) (*CIDR, error) {
+	f := &CIDR{Field: field}
+	for _, cidr := range cidrs {
+		prefix, err := parseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		f.Prefixes = append(f.Prefixes, prefix)
+	}
+	return f, nil
+}
+
+// Filter checks the field value to be an IP address within the prefixes.
+func (f *CIDR) Filter(entry *Entry) *Entry {
+	val, err := entry.StringField(f.Field)
+	if err != nil {
+		return nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(val))
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	for _, prefix := range f.Prefixes {
+		if prefix.Contains(addr) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *CIDR) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// DefaultStatusField is the field used by StatusClass if none is configured.
+const DefaultStatusField = "status"
+
+// StatusClass implements the Filter interface for entries with HTTP status of
+// given classes, e.g. 4 for 4xx and 5 for 5xx.
+type StatusClass struct {
+	Field   string
+	Classes []int
+}
+
+// NewStatusClass creates a StatusClass filter for the DefaultStatusField.
+func NewStatusClass(classes ...int) *StatusClass {
+	return &StatusClass{Field: DefaultStatusField, Classes: classes}
+}
+
+// Filter checks the status field value to be of any of the classes.
+func (f *StatusClass) Filter(entry *Entry) *Entry {
+	field := f.Field
+	if field == "" {
+		field = DefaultStatusField
+	}
+	status, err := entry.IntField(field)
+	if err != nil {
+		return nil
+	}
+	for _, class := range f.Classes {
+		if status/100 == class {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *StatusClass) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
@@ -0,0 +1,73 @@
+package gonx
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync/atomic"
+)
+
+// Sample implements the Filter interface to keep a fraction of entries.
+//
+// By default it keeps random Rate fraction of entries, e.g. 0.01 keeps about 1%
+// of them. If EveryN is set, every Nth entry is kept instead. If Fields are set,
+// the decision is made by the hash of these fields values (see FieldsHash), so
+// the same client or request is always in or out of the sample across files
+// and runs. Change Salt to get another consistent sample of the same Rate.
+type Sample struct {
+	Rate   float64
+	EveryN uint64
+	Fields []string
+	Salt   string
+	count  uint64
+}
+
+// Filter keeps the entry if it is in the sample.
+func (f *Sample) Filter(entry *Entry) *Entry {
+	switch {
+	case f.EveryN > 0:
+		if atomic.AddUint64(&f.count, 1)%f.EveryN != 0 {
+			return nil
+		}
+	case len(f.Fields) > 0:
+		h := fnv.New64a()
+		h.Write([]byte(f.Salt))
+		h.Write([]byte(entry.FieldsHash(f.Fields)))
+		if float64(mix64(h.Sum64())) >= f.Rate*math.MaxUint64 {
+			return nil
+		}
+	default:
+		if rand.Float64() >= f.Rate {
+			return nil
+		}
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Filter.
+func (f *Sample) Reduce(input chan *Entry, output chan *Entry) {
+	reduceFilter(f, input, output)
+}
+
+// Scale returns the factor to scale counters of sampled entries back up, use
+// it with NewScaled.
+func (f *Sample) Scale() float64 {
+	if f.EveryN > 0 {
+		return float64(f.EveryN)
+	}
+	if f.Rate <= 0 {
+		return 0
+	}
+	return 1 / f.Rate
+}
+
+// mix64 is the murmur3 finalizer, it spreads similar FNV hashes of similar
+// values over the whole range.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
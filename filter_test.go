@@ -1,6 +1,9 @@
 package gonx
 
 import (
+	"fmt"
+	"math"
+	"regexp"
 	"testing"
 	"time"
 
@@ -62,6 +65,68 @@ func TestFilter(t *testing.T) {
 				// entry is out of datetime range
 				So(filter.Filter(may), ShouldBeNil)
 			})
+
+			Convey("Open ended", func() {
+				filter := &Datetime{
+					Field:  "timestamp",
+					Format: time.RFC3339,
+					Start:  start,
+				}
+				So(filter.Filter(mar), ShouldEqual, mar)
+				So(filter.Filter(may), ShouldEqual, may)
+
+				filter = &Datetime{Field: "timestamp", Format: time.RFC3339}
+				So(filter.Filter(jan), ShouldEqual, jan)
+			})
+
+			Convey("Inclusive end", func() {
+				filter := &Datetime{
+					Field:      "timestamp",
+					Format:     time.RFC3339,
+					Start:      start,
+					End:        end,
+					IncludeEnd: true,
+				}
+				So(filter.Filter(may), ShouldEqual, may)
+			})
+
+			Convey("Detect layout and timezone", func() {
+				filter := &Datetime{
+					Field:    "timestamp",
+					Start:    time.Date(2013, time.November, 8, 13, 0, 0, 0, time.UTC),
+					End:      time.Date(2013, time.November, 8, 14, 0, 0, 0, time.UTC),
+					Location: time.FixedZone("MSK", 3*60*60),
+				}
+				local := NewEntry(Fields{"timestamp": "08/Nov/2013:13:39:18 +0000"})
+				So(filter.Filter(local), ShouldEqual, local)
+				iso := NewEntry(Fields{"timestamp": "2013-11-08T16:39:18+03:00"})
+				So(filter.Filter(iso), ShouldEqual, iso)
+				// Timestamp without zone is in filter's Location, 16:39 MSK is 13:39 UTC
+				errorLog := NewEntry(Fields{"timestamp": "2013/11/08 16:39:18"})
+				So(filter.Filter(errorLog), ShouldEqual, errorLog)
+				utc := NewEntry(Fields{"timestamp": "2013/11/08 13:39:18"})
+				So(filter.Filter(utc), ShouldBeNil)
+			})
+
+			Convey("Bad timestamps", func() {
+				bad := NewEntry(Fields{"timestamp": "yesterday"})
+				missing := NewEntry(Fields{"foo": "1"})
+				filter := &Datetime{Field: "timestamp", Start: start}
+				So(filter.Filter(bad), ShouldBeNil)
+				So(filter.Filter(missing), ShouldBeNil)
+
+				filter.BadTime = KeepBadTime
+				So(filter.Filter(bad), ShouldEqual, bad)
+				So(filter.Filter(missing), ShouldEqual, missing)
+
+				var errs []error
+				ErrorHandler = func(err error) { errs = append(errs, err) }
+				defer func() { ErrorHandler = nil }()
+				filter.BadTime = ReportBadTime
+				So(filter.Filter(bad), ShouldBeNil)
+				So(filter.Filter(missing), ShouldBeNil)
+				So(errs, ShouldHaveLength, 2)
+			})
 		})
 
 		Convey("Deal with input channel", func() {
@@ -121,3 +186,169 @@ func TestFilter(t *testing.T) {
 		})
 	})
 }
+
+func TestBuiltinFilters(t *testing.T) {
+	Convey("Test built-in filters", t, func() {
+		api := NewEntry(Fields{
+			"status":          "503",
+			"request_uri":     "/api/users",
+			"request_time":    "2.5",
+			"remote_addr":     "10.1.2.3",
+			"http_user_agent": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		})
+		page := NewEntry(Fields{
+			"status":          "200",
+			"request_uri":     "/index.html",
+			"request_time":    "0.1",
+			"remote_addr":     "2001:db8::1",
+			"http_user_agent": "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0",
+		})
+
+		Convey("Field filters", func() {
+			equal := &Equal{Field: "status", Value: "200"}
+			So(equal.Filter(api), ShouldBeNil)
+			So(equal.Filter(page), ShouldEqual, page)
+
+			match := &Match{Field: "request_uri", Regexp: regexp.MustCompile(`^/api/`)}
+			So(match.Filter(api), ShouldEqual, api)
+			So(match.Filter(page), ShouldBeNil)
+
+			slow := &Range{Field: "request_time", Min: 1, Max: math.Inf(1)}
+			So(slow.Filter(api), ShouldEqual, api)
+			So(slow.Filter(page), ShouldBeNil)
+
+			cidr, err := NewCIDR("remote_addr", "10.0.0.0/8", "2001:db8::/32")
+			So(err, ShouldBeNil)
+			So(cidr.Filter(api), ShouldEqual, api)
+			So(cidr.Filter(page), ShouldEqual, page)
+			_, err = NewCIDR("remote_addr", "10.0.0.0/33")
+			So(err, ShouldNotBeNil)
+
+			errors := NewStatusClass(4, 5)
+			So(errors.Filter(api), ShouldEqual, api)
+			So(errors.Filter(page), ShouldBeNil)
+
+			bot := &Bot{}
+			So(bot.Filter(api), ShouldEqual, api)
+			So(bot.Filter(page), ShouldBeNil)
+			So(IsBot("curl/8.0.1"), ShouldBeTrue)
+			So(IsBot("-"), ShouldBeTrue)
+		})
+
+		Convey("Combinators", func() {
+			apiErrors := NewAnd(NewStatusClass(5), &Match{Field: "request_uri", Regexp: regexp.MustCompile(`^/api/`)})
+			So(apiErrors.Filter(api), ShouldEqual, api)
+			So(apiErrors.Filter(page), ShouldBeNil)
+
+			any := NewOr(NewStatusClass(5), &Equal{Field: "request_uri", Value: "/index.html"})
+			So(any.Filter(api), ShouldEqual, api)
+			So(any.Filter(page), ShouldEqual, page)
+
+			humans := NewNot(&Bot{})
+			So(humans.Filter(api), ShouldBeNil)
+			So(humans.Filter(page), ShouldEqual, page)
+		})
+
+		Convey("Sample", func() {
+			So((&Sample{Rate: 0}).Filter(api), ShouldBeNil)
+			So((&Sample{Rate: 1}).Filter(api), ShouldEqual, api)
+		})
+
+		Convey("Filter channel", func() {
+			input := make(chan *Entry, 2)
+			input <- api
+			input <- page
+			close(input)
+			output := make(chan *Entry, 1)
+
+			chain := NewChain(NewNot(&Bot{}), &Count{})
+			chain.Reduce(input, output)
+
+			result := <-output
+			count, err := result.FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestSample(t *testing.T) {
+	Convey("Test sampling", t, func() {
+		var entries []*Entry
+		for i := 0; i < 1000; i++ {
+			entries = append(entries, NewEntry(Fields{
+				"client": fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+				"bytes":  "10",
+			}))
+		}
+		sampled := func(f Filter) (kept []*Entry) {
+			for _, entry := range entries {
+				if f.Filter(entry) != nil {
+					kept = append(kept, entry)
+				}
+			}
+			return
+		}
+
+		Convey("Every Nth", func() {
+			sample := &Sample{EveryN: 10}
+			kept := sampled(sample)
+			So(kept, ShouldHaveLength, 100)
+			So(kept[0], ShouldEqual, entries[9])
+			So(sample.Scale(), ShouldEqual, 10)
+		})
+
+		Convey("Consistent hashing", func() {
+			sample := &Sample{Rate: 0.1, Fields: []string{"client"}}
+			kept := sampled(sample)
+			So(len(kept), ShouldBeBetween, 50, 150)
+			// The same entries are sampled on each run
+			So(sampled(&Sample{Rate: 0.1, Fields: []string{"client"}}), ShouldResemble, kept)
+			// Another salt gives another sample
+			So(sampled(&Sample{Rate: 0.1, Fields: []string{"client"}, Salt: "x"}), ShouldNotResemble, kept)
+			So(sampled(&Sample{Rate: 1, Fields: []string{"client"}}), ShouldHaveLength, 1000)
+		})
+
+		Convey("Scale results", func() {
+			input := make(chan *Entry, len(entries))
+			for _, entry := range entries {
+				input <- entry
+			}
+			close(input)
+			output := make(chan *Entry, 1)
+
+			sample := &Sample{EveryN: 4}
+			chain := NewChain(sample, NewScaled(sample.Scale(), &Count{}, &Sum{map[string]string{"bytes": "bytes"}}))
+			chain.Reduce(input, output)
+
+			result := <-output
+			count, err := result.FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1000)
+			sum, err := result.FloatField("bytes")
+			So(err, ShouldBeNil)
+			So(sum, ShouldEqual, 10000)
+		})
+
+		Convey("Pass through results of non-additive reducers", func() {
+			input := make(chan *Entry, len(entries))
+			for _, entry := range entries {
+				input <- entry
+			}
+			close(input)
+			output := make(chan *Entry, 1)
+
+			sample := &Sample{EveryN: 4}
+			scaled := NewScaled(sample.Scale(), &Count{}, &Avg{map[string]string{"avg_bytes": "bytes"}})
+			NewChain(sample, scaled).Reduce(input, output)
+
+			result := <-output
+			count, err := result.FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1000)
+			avg, err := result.FloatField("avg_bytes")
+			So(err, ShouldBeNil)
+			So(avg, ShouldEqual, 10)
+		})
+	})
+}
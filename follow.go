@@ -0,0 +1,208 @@
+package gonx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/dreamsxin/gonx/follower"
+)
+
+// ParseError is a line parsing error with the line position. Offset is the
+// file offset of the line start.
+type ParseError struct {
+	Source string
+	Offset int64
+	Text   string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v:%d: %v", e.Source, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// FollowConfig configures FollowReader. Workers is the number of goroutines
+// parsing lines concurrently, all available CPUs are used if it is zero.
+// Entries get the line Position if Positions is set.
+type FollowConfig struct {
+	follower.Config
+	Workers   int
+	Positions bool
+}
+
+// errorsBuffer is the number of parse errors kept until they are received.
+const errorsBuffer = 100
+
+// FollowReader tails a growing log file and parses new lines as they are
+// written, like `tail -F`. Entries are available one by one with Read or as a
+// channel to feed reducers, e.g. Window for live statistics per period.
+//
+// Lines are parsed concurrently, so entries may come slightly out of order.
+// Parse errors are sent to Errors channel, or to ErrorHandler if it is full.
+type FollowReader struct {
+	follower  *follower.Follower
+	parser    StringParser
+	positions bool
+	entries   chan *Entry
+	errors    chan error
+}
+
+// NewFollowReader starts following the file at path with the parser.
+func NewFollowReader(path string, parser StringParser, config FollowConfig) (*FollowReader, error) {
+	f, err := follower.New(context.Background(), path, config.Config)
+	if err != nil {
+		return nil, err
+	}
+	r := &FollowReader{
+		follower:  f,
+		parser:    parser,
+		positions: config.Positions,
+		entries:   make(chan *Entry, 10),
+		errors:    make(chan error, errorsBuffer),
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.parse()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(r.entries)
+		close(r.errors)
+	}()
+	return r, nil
+}
+
+func (r *FollowReader) parse() {
+	for line := range r.follower.Lines() {
+		text := line.String()
+		entry, err := r.parser.ParseString(text)
+		if err != nil {
+			r.error(&ParseError{Source: line.Source(), Offset: line.Start(), Text: text, Err: err})
+			continue
+		}
+		if entry != nil {
+			if r.positions {
+				entry.Position = &Position{Source: line.Source(), Offset: line.Start(), Raw: text}
+			}
+			r.entries <- entry
+		}
+	}
+}
+
+func (r *FollowReader) error(err error) {
+	select {
+	case r.errors <- err:
+	default:
+		handleError(err)
+	}
+}
+
+// Read next parsed Entry. It blocks until a new line is written to the file.
+// Return EOF after the reader is closed or the file is removed, or the error
+// which stopped following.
+func (r *FollowReader) Read() (*Entry, error) {
+	entry, ok := <-r.entries
+	if !ok {
+		if err := r.follower.Err(); err != nil && !errors.Is(err, follower.ErrPolling) {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return entry, nil
+}
+
+// Entries returns the channel of parsed entries. It is closed when following
+// stops, so it could be passed to Reducer.Reduce directly.
+func (r *FollowReader) Entries() chan *Entry {
+	return r.entries
+}
+
+// Errors returns the channel of parse errors, see ParseError.
+func (r *FollowReader) Errors() <-chan error {
+	return r.errors
+}
+
+// Reduce runs the reducer over followed entries and returns its output channel.
+func (r *FollowReader) Reduce(reducer Reducer) chan *Entry {
+	output := make(chan *Entry)
+	go reducer.Reduce(r.entries, output)
+	return output
+}
+
+// Offset returns the file offset after the last line taken for parsing.
+func (r *FollowReader) Offset() int64 {
+	return r.follower.Offset()
+}
+
+// Err returns the error which stopped following, if any, see follower.Follower.Err.
+func (r *FollowReader) Err() error {
+	return r.follower.Err()
+}
+
+// Close stops following the file. Entries channel is closed after the lines
+// taken already are parsed.
+func (r *FollowReader) Close() {
+	r.follower.Close()
+}
+
+// FollowInput implements the Input interface for a followed file, so it could
+// be processed with MapReduceInput. Lines are numbered from the start of
+// following. ReadLines returns when the follower is closed. Entries get the
+// line Position if Positions is set.
+type FollowInput struct {
+	Positions bool
+	follower  *follower.Follower
+}
+
+// NewFollowInput starts following the file at path.
+func NewFollowInput(path string, config follower.Config) (*FollowInput, error) {
+	f, err := follower.New(context.Background(), path, config)
+	if err != nil {
+		return nil, err
+	}
+	return &FollowInput{follower: f}, nil
+}
+
+// ReadLines implements the Input interface.
+func (i *FollowInput) ReadLines(lines chan<- Line) error {
+	var number int64
+	for line := range i.follower.Lines() {
+		number++
+		lines <- Line{Source: line.Source(), Number: number, Offset: line.Start(), Text: line.String()}
+	}
+	return i.follower.Err()
+}
+
+// Tag implements the Tagger interface.
+func (i *FollowInput) Tag(entry *Entry, line Line) {
+	if i.Positions {
+		entry.Position = line.Position()
+		// lines are counted from the start of following only
+		entry.Position.Line = 0
+	}
+}
+
+// Offset returns the file offset after the last line read.
+func (i *FollowInput) Offset() int64 {
+	return i.follower.Offset()
+}
+
+// Close stops following the file.
+func (i *FollowInput) Close() {
+	i.follower.Close()
+}
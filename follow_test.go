@@ -0,0 +1,112 @@
+package gonx
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dreamsxin/gonx/follower"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFollowReader(t *testing.T) {
+	Convey("Test following a log file", t, func() {
+		name := filepath.Join(t.TempDir(), "access.log")
+		So(os.WriteFile(name, []byte("1 foo\n2 bar\n"), 0644), ShouldBeNil)
+		write := func(data string) {
+			file, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+			So(err, ShouldBeNil)
+			_, err = file.WriteString(data)
+			So(err, ShouldBeNil)
+			So(file.Close(), ShouldBeNil)
+		}
+		parser := NewParser("$id $name")
+		config := FollowConfig{Config: follower.Config{Whence: io.SeekStart}, Workers: 1}
+
+		Convey("Read entries and errors", func() {
+			reader, err := NewFollowReader(name, parser, config)
+			So(err, ShouldBeNil)
+
+			entry, err := reader.Read()
+			So(err, ShouldBeNil)
+			id, _ := entry.StringField("id")
+			So(id, ShouldEqual, "1")
+			entry, err = reader.Read()
+			So(err, ShouldBeNil)
+			id, _ = entry.StringField("id")
+			So(id, ShouldEqual, "2")
+
+			write("garbage\n3 baz\n")
+			entry, err = reader.Read()
+			So(err, ShouldBeNil)
+			name, _ := entry.StringField("name")
+			So(name, ShouldEqual, "baz")
+
+			var parseErr *ParseError
+			err = <-reader.Errors()
+			So(errors.As(err, &parseErr), ShouldBeTrue)
+			So(parseErr.Text, ShouldEqual, "garbage")
+			So(parseErr.Offset, ShouldEqual, 12)
+			So(parseErr.Error(), ShouldEndWith, "access.log:12: "+parseErr.Err.Error())
+
+			reader.Close()
+			_, err = reader.Read()
+			So(err, ShouldEqual, io.EOF)
+		})
+
+		Convey("Entry positions", func() {
+			config.Positions = true
+			reader, err := NewFollowReader(name, parser, config)
+			So(err, ShouldBeNil)
+			defer reader.Close()
+
+			reader.Read()
+			entry, err := reader.Read()
+			So(err, ShouldBeNil)
+			So(entry.Position, ShouldResemble, &Position{Source: name, Offset: 6, Raw: "2 bar"})
+			So(entry.Position.String(), ShouldEqual, name+"@6")
+		})
+
+		Convey("Live windows", func() {
+			So(os.WriteFile(name, []byte{}, 0644), ShouldBeNil)
+			reader, err := NewFollowReader(name, NewParser("$time $name"), config)
+			So(err, ShouldBeNil)
+			write("2024-01-01T10:00:00Z a\n2024-01-01T10:00:30Z b\n2024-01-01T10:01:00Z c\n")
+
+			window := NewWindow("time", time.Minute, &Count{})
+			window.Location = time.UTC
+			output := reader.Reduce(window)
+			select {
+			case result := <-output:
+				count, _ := result.FloatField("count")
+				So(count, ShouldEqual, 2)
+			case <-time.After(5 * time.Second):
+				So("timeout", ShouldBeEmpty)
+			}
+
+			reader.Close()
+			result := <-output
+			start, _ := result.StringField(DefaultWindowStartField)
+			So(start, ShouldEqual, "2024-01-01T10:01:00Z")
+		})
+
+		Convey("MapReduce input", func() {
+			input, err := NewFollowInput(name, follower.Config{Whence: io.SeekStart})
+			So(err, ShouldBeNil)
+			output := MapReduceInput(input, parser, &Count{})
+			write("3 baz\n")
+			deadline := time.Now().Add(5 * time.Second)
+			for input.Offset() < 18 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			input.Close()
+
+			result := <-output
+			count, _ := result.FloatField("count")
+			So(count, ShouldEqual, 3)
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package follower
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fingerprintSize is the number of leading bytes identifying the file content.
+const fingerprintSize = 1024
+
+// Checkpoint is the position of the last delivered line in a followed file. The
+// file is identified by device and inode numbers along with a fingerprint of its
+// first bytes, so rotation or truncation could be detected on resume.
+type Checkpoint struct {
+	Offset          int64  `json:"offset"`
+	Device          uint64 `json:"device"`
+	Inode           uint64 `json:"inode"`
+	Fingerprint     string `json:"fingerprint"`
+	FingerprintSize int    `json:"fingerprint_size"`
+}
+
+// CheckpointStore persists checkpoints by file name.
+type CheckpointStore interface {
+	Load(filename string) (Checkpoint, bool, error)
+	Save(filename string, cp Checkpoint) error
+}
+
+// FileCheckpointStore keeps checkpoints of all files in a single JSON file. The
+// file is replaced atomically on each save, so it is never left half written.
+type FileCheckpointStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a store in the given file.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{Path: path}
+}
+
+func (s *FileCheckpointStore) read() (map[string]Checkpoint, error) {
+	checkpoints := make(map[string]Checkpoint)
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, err
+	}
+	return checkpoints, nil
+}
+
+// Load returns the checkpoint of the file or false if there is none.
+func (s *FileCheckpointStore) Load(filename string) (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoints, err := s.read()
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	cp, ok := checkpoints[filename]
+	return cp, ok, nil
+}
+
+// Save stores the checkpoint of the file.
+func (s *FileCheckpointStore) Save(filename string, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	checkpoints, err := s.read()
+	if err != nil {
+		return err
+	}
+	checkpoints[filename] = cp
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.Path, data)
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over the target.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// fingerprint returns the hash of up to size leading bytes of the file and the
+// number of bytes hashed.
+func fingerprint(file io.ReaderAt, size int) (string, int, error) {
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return hex.EncodeToString(sum[:]), n, nil
+}
+
+// newCheckpoint returns the checkpoint of the file at given offset.
+func newCheckpoint(file *os.File, offset int64) (Checkpoint, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	cp := Checkpoint{Offset: offset}
+	cp.Device, cp.Inode = fileID(fi)
+	size := fingerprintSize
+	if offset < int64(size) {
+		// Only delivered data is known to stay the same
+		size = int(offset)
+	}
+	cp.Fingerprint, cp.FingerprintSize, err = fingerprint(file, size)
+	return cp, err
+}
+
+// resumeOffset returns the offset to resume reading the file from or false if the
+// checkpoint does not belong to it, i.e. the file was rotated or truncated.
+func resumeOffset(file *os.File, cp Checkpoint) (int64, bool, error) {
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+	if dev, ino := fileID(fi); (dev != 0 || ino != 0) && (dev != cp.Device || ino != cp.Inode) {
+		return 0, false, nil
+	}
+	if fi.Size() < cp.Offset {
+		return 0, false, nil
+	}
+	sum, n, err := fingerprint(file, cp.FingerprintSize)
+	if err != nil || n != cp.FingerprintSize || sum != cp.Fingerprint {
+		return 0, false, err
+	}
+	return cp.Offset, true, nil
+}
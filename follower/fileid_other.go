@@ -0,0 +1,13 @@
+//go:build !unix
+
+package follower
+
+import (
+	"os"
+)
+
+// fileID returns zeros where device and inode numbers are not available, so
+// files are identified by the fingerprint only.
+func fileID(fi os.FileInfo) (uint64, uint64) {
+	return 0, 0
+}
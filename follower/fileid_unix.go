@@ -0,0 +1,16 @@
+//go:build unix
+
+package follower
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns device and inode numbers of the file.
+func fileID(fi os.FileInfo) (uint64, uint64) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), uint64(st.Ino)
+	}
+	return 0, 0
+}
@@ -3,9 +3,14 @@ package follower
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -16,9 +21,33 @@ const (
 	peekSize = 1024
 )
 
+// DefaultCheckpointInterval is the period of saving checkpoints if none is set.
+const DefaultCheckpointInterval = 5 * time.Second
+
+// DefaultRotateGrace is the time a rotated file has to be idle before switching
+// to the new one if none is set.
+const DefaultRotateGrace = 2 * time.Second
+
+// DefaultPollInterval is the period of checking the file for changes in the
+// polling mode if none is set.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// DefaultPollMaxInterval is the longest period of checking an idle file in the
+// polling mode if none is set.
+const DefaultPollMaxInterval = 5 * time.Second
+
+// drainInterval is the period of reading a rotated file.
+const drainInterval = 100 * time.Millisecond
+
+// watchFallback is the period of checking the file if no events arrive.
+const watchFallback = 10 * time.Second
+
 type Line struct {
 	bytes     []byte
 	discarded int
+	start     int64
+	offset    int64
+	source    string
 }
 
 func (l *Line) Bytes() []byte {
@@ -33,10 +62,56 @@ func (l *Line) Discarded() int {
 	return l.discarded
 }
 
+// Start returns the file offset of the line start, after discarded bytes.
+func (l *Line) Start() int64 {
+	return l.start
+}
+
+// Offset returns the file offset right after the line.
+func (l *Line) Offset() int64 {
+	return l.offset
+}
+
+// Source returns the name of the file the line was read from.
+func (l *Line) Source() string {
+	return l.source
+}
+
+// Config of the Follower. Offset and Whence set the starting position.
+//
+// If Checkpoints store is set, the offset of the last delivered line is saved
+// every CheckpointInterval and on close. On start the follower resumes from the
+// saved offset unless the file was rotated or truncated in the meantime, then
+// it is read from the beginning. If the file was rotated to RotatedName, which
+// defaults to the name with ".1" suffix, the rest of the rotated file is read
+// first.
+//
+// With Reopen set, a rotated file is read further until the new one appears and
+// the rotated one is idle for RotateGrace, so lines written before the writer
+// reopened its log are not lost. Rotation by copytruncate is detected as well.
+//
+// Changes are watched with fsnotify unless Poll is set or watching the file
+// fails, e.g. when inotify limits are reached. Polling is meant for filesystems
+// where no events are delivered, like NFS or some FUSE mounts. The file is then
+// checked every PollInterval, doubled while it stays idle up to PollMaxInterval.
+//
+// If Multiline is set, lines are grouped into records, each delivered as a
+// single Line. The offset of a pending record is not saved to checkpoints.
 type Config struct {
-	Offset int64
-	Whence int
-	Reopen bool
+	Offset      int64
+	Whence      int
+	Reopen      bool
+	RotateGrace time.Duration
+	RotatedName string
+
+	Poll            bool
+	PollInterval    time.Duration
+	PollMaxInterval time.Duration
+
+	Multiline *Multiline
+
+	Checkpoints        CheckpointStore
+	CheckpointInterval time.Duration
 }
 
 type Follower struct {
@@ -48,16 +123,58 @@ type Follower struct {
 	config   Config
 	reader   *bufio.Reader
 	watcher  *fsnotify.Watcher
+	polling  atomic.Bool
 	offset   int64
-	closeCh  chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closed   atomic.Bool
+	done     chan struct{}
+	mu       sync.Mutex
+	watchErr error
+	stats    stats
+
+	delivered      atomic.Int64
+	lastCheckpoint time.Time
+	head           []byte
+	pending        bool
+
+	assembler       *Assembler
+	recordStart     int64
+	recordEnd       int64
+	recordDiscarded int
+	recordTime      time.Time
+}
+
+// Stats are counters of a Follower activity.
+type Stats struct {
+	// Bytes is the number of bytes read, including discarded ones.
+	Bytes int64
+	// Lines is the number of delivered lines.
+	Lines int64
+	// Rotations is the number of times the follower switched to a new file.
+	Rotations int64
+	// Truncations is the number of times the file was truncated or rewritten.
+	Truncations int64
+	// Discarded is the number of NUL bytes discarded.
+	Discarded int64
 }
 
-func New(filename string, config Config) (*Follower, error) {
+type stats struct {
+	bytes, lines, rotations, truncations, discarded atomic.Int64
+}
+
+// New starts following the file. Following stops when the context is done or
+// on Close.
+func New(ctx context.Context, filename string, config Config) (*Follower, error) {
 	t := &Follower{
 		filename: filename,
 		lines:    make(chan Line),
 		config:   config,
-		closeCh:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	t.polling.Store(config.Poll)
+	if config.Multiline != nil {
+		t.assembler = NewAssembler(config.Multiline)
 	}
 
 	err := t.reopen()
@@ -65,6 +182,8 @@ func New(filename string, config Config) (*Follower, error) {
 		return nil, err
 	}
 
+	t.ctx, t.cancel = context.WithCancel(ctx)
+
 	go t.once.Do(t.run)
 
 	return t, nil
@@ -74,82 +193,147 @@ func (t *Follower) Lines() chan Line {
 	return t.lines
 }
 
+// Offset returns the file offset after the last delivered line.
+func (t *Follower) Offset() int64 {
+	return t.delivered.Load()
+}
+
+// ErrPolling is reported by Err, wrapping the fsnotify error, if watching the
+// file failed and it is polled instead.
+var ErrPolling = errors.New("watching failed, polling instead")
+
+// Err returns the error which stopped following. It is the context error if
+// the context was done before Close. Otherwise, if the follower fell back to
+// polling, it is ErrPolling with the reason.
 func (t *Follower) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err == nil && t.watchErr != nil {
+		return fmt.Errorf("%w: %v", ErrPolling, t.watchErr)
+	}
 	return t.err
 }
 
+// fallback switches to polling after watching the file failed.
+func (t *Follower) fallback(err error) {
+	t.mu.Lock()
+	if t.watchErr == nil {
+		t.watchErr = err
+	}
+	t.mu.Unlock()
+	t.polling.Store(true)
+}
+
+// Polling reports whether the file is polled for changes instead of watched.
+func (t *Follower) Polling() bool {
+	return t.polling.Load()
+}
+
+// Stats returns the follower counters.
+func (t *Follower) Stats() Stats {
+	return Stats{
+		Bytes:       t.stats.bytes.Load(),
+		Lines:       t.stats.lines.Load(),
+		Rotations:   t.stats.rotations.Load(),
+		Truncations: t.stats.truncations.Load(),
+		Discarded:   t.stats.discarded.Load(),
+	}
+}
+
+// Close stops following and waits until the file is closed. It is safe to call
+// it several times. Lines channel is closed after that.
 func (t *Follower) Close() {
-	t.closeCh <- struct{}{}
+	t.closed.Store(true)
+	t.cancel()
+	<-t.done
 }
 
 func (t *Follower) run() {
-	t.close(t.follow())
+	defer close(t.done)
+	defer t.cancel()
+	err := t.follow()
+	if err == context.Canceled && t.closed.Load() {
+		// stopped by Close
+		err = nil
+	}
+	t.close(err)
 }
 
 func (t *Follower) follow() error {
-	_, err := t.file.Seek(t.config.Offset, t.config.Whence)
+	err := t.seekStart()
 	if err != nil {
 		return err
 	}
 
+	// a write event arriving while lines are read is kept, further ones are
+	// debounced. Both channels stay nil when polling.
 	var (
-		eventChan = make(chan fsnotify.Event)
-		errChan   = make(chan error, 1)
+		eventChan chan fsnotify.Event
+		errChan   chan error
+		wg        sync.WaitGroup
 	)
 
-	t.watcher, err = fsnotify.NewWatcher()
-	if err != nil {
-		return err
+	// stop watching and wait for the events goroutine on exit
+	defer wg.Wait()
+	defer t.closeWatcher()
+	defer t.cancel()
+
+	if t.config.Poll {
+		t.polling.Store(true)
+	} else if err := t.watch(); err != nil {
+		t.fallback(err)
+	} else {
+		eventChan = make(chan fsnotify.Event, 1)
+		errChan = make(chan error, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.watchFileEvents(eventChan, errChan)
+		}()
 	}
 
-	defer t.watcher.Close()
-	go t.watchFileEvents(eventChan, errChan)
+	var checkpoints <-chan time.Time
+	if t.config.Checkpoints != nil {
+		ticker := time.NewTicker(t.checkpointInterval())
+		defer ticker.Stop()
+		checkpoints = ticker.C
+	}
 
-	t.watcher.Add(t.filename)
+	// we resumed from the rotated file, so finish it first
+	if t.pending {
+		if err := t.rotate(eventChan); err != nil {
+			return err
+		}
+	}
 
+	interval := t.pollInterval()
 	for {
-		for {
-			// discard leading NUL bytes
-			var discarded int
-
-			for {
-				b, _ := t.reader.Peek(peekSize)
-				i := bytes.LastIndexByte(b, '\x00')
-
-				if i > 0 {
-					n, _ := t.reader.Discard(i + 1)
-					discarded += n
-				}
-
-				if i+1 < peekSize {
-					break
-				}
-			}
+		n, err := t.readLines()
+		if err != nil {
+			return err
+		}
 
-			s, err := t.reader.ReadBytes('\n')
-			if err != nil && err != io.EOF {
-				return err
+		wait := watchFallback
+		if t.polling.Load() {
+			// back off while the file is idle
+			if n > 0 {
+				interval = t.pollInterval()
+			} else {
+				interval = min(2*interval, t.pollMaxInterval())
 			}
+			wait = interval
+		}
 
-			// if we encounter EOF before a line delimiter,
-			// ReadBytes() will return the remaining bytes,
-			// so push them back onto the buffer, rewind
-			// our seek position, and wait for further file changes.
-			// we also have to save our dangling byte count in the event
-			// that we want to re-open the file and seek to the end
-			if err == io.EOF {
-				l := len(s)
-
-				t.offset, err = t.file.Seek(-int64(l), io.SeekCurrent)
-				if err != nil {
+		// deliver the last record once no more lines are written
+		if t.recordPending() {
+			idle := time.Since(t.recordTime)
+			if idle >= t.config.Multiline.timeout() {
+				if err := t.flushRecord(); err != nil {
 					return err
 				}
-
-				t.reader.Reset(t.file)
-				break
+				continue
 			}
-
-			t.sendLine(s, discarded)
+			wait = min(wait, t.config.Multiline.timeout()-idle)
 		}
 
 		// we're now at EOF, so wait for changes
@@ -162,43 +346,40 @@ func (t *Follower) follow() error {
 				fallthrough
 
 			case fsnotify.Write:
-				fi, err := t.file.Stat()
+				// file was truncated or rewritten, e.g. by copytruncate
+				// rotation, seek to the beginning
+				truncated, err := t.truncated()
 				if err != nil {
-					if !os.IsNotExist(err) {
-						return err
-					}
-
-					// it's possible that an unlink can cause fsnotify.Chmod,
-					// so attempt to rewatch if the file is missing
-					if err := t.rewatch(); err != nil {
-						return err
-					}
-
-					continue
+					return err
 				}
 
-				// file was truncated, seek to the beginning
-				if t.offset > fi.Size() {
-					t.offset, err = t.file.Seek(0, io.SeekStart)
-					if err != nil {
+				if truncated {
+					if err := t.truncate(); err != nil {
 						return err
 					}
-
-					t.reader.Reset(t.file)
 				}
 
 				continue
 
-			// if a file is removed or renamed
-			// and re-opening is desired, see if it appears
-			// again within a 1 second deadline. this should be enough time
-			// to see the file again for log rotation programs with this behavior
+			// if a file is removed or renamed and re-opening is desired,
+			// keep reading the old file until the writer reopens its log,
+			// then switch to the new one.
 			default:
+				rotated, err := t.rotated()
+				if err != nil {
+					return err
+				}
+
+				// stale event of the previous file
+				if !rotated {
+					continue
+				}
+
 				if !t.config.Reopen {
-					return nil
+					return t.flushRecord()
 				}
 
-				if err := t.rewatch(); err != nil {
+				if err := t.rotate(eventChan); err != nil {
 					return err
 				}
 
@@ -209,30 +390,47 @@ func (t *Follower) follow() error {
 		case err := <-errChan:
 			return err
 
-		// a request to stop
-		case <-t.closeCh:
-			t.watcher.Remove(t.filename)
-			return nil
-
-		// fall back to 10 second polling if we haven't received any fsevents
-		// stat the file, if it's still there, just continue and try to read bytes
-		// if not, go through our re-opening routine
-		case <-time.After(10 * time.Second):
-			fi1, err := t.file.Stat()
-			if err != nil && !os.IsNotExist(err) {
+		case <-checkpoints:
+			if err := t.checkpoint(true); err != nil {
 				return err
 			}
 
-			fi2, err := os.Stat(t.filename)
-			if err != nil && !os.IsNotExist(err) {
+			continue
+
+		// a request to stop
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+
+		// poll the file, or fall back to 10 second polling if we haven't
+		// received any fsevents. stat the file, if it's still there, just
+		// continue and try to read bytes, if not, go through our re-opening
+		// routine
+		case <-time.After(wait):
+			rotated, err := t.rotated()
+			if err != nil {
 				return err
 			}
 
-			if os.SameFile(fi1, fi2) {
+			if !rotated {
+				truncated, err := t.truncated()
+				if err != nil {
+					return err
+				}
+
+				if truncated {
+					if err := t.truncate(); err != nil {
+						return err
+					}
+				}
+
 				continue
 			}
 
-			if err := t.rewatch(); err != nil {
+			if !t.config.Reopen {
+				return t.flushRecord()
+			}
+
+			if err := t.rotate(eventChan); err != nil {
 				return err
 			}
 
@@ -241,16 +439,284 @@ func (t *Follower) follow() error {
 	}
 }
 
+// readLines sends all complete lines available in the file and returns the
+// number of bytes read.
+func (t *Follower) readLines() (int64, error) {
+	start := t.offset
+	for {
+		// discard leading NUL bytes
+		var discarded int
+
+		for {
+			b, _ := t.reader.Peek(peekSize)
+			i := bytes.LastIndexByte(b, '\x00')
+
+			if i > 0 {
+				t.addHead(b[:i+1])
+				n, _ := t.reader.Discard(i + 1)
+				discarded += n
+				t.offset += int64(n)
+				t.stats.bytes.Add(int64(n))
+				t.stats.discarded.Add(int64(n))
+			}
+
+			if i+1 < peekSize {
+				break
+			}
+		}
+
+		lineStart := t.offset
+		s, err := t.reader.ReadBytes('\n')
+		if err == errRewritten {
+			// the bytes read may belong to the new content
+			read := t.offset - start
+			if err := t.truncate(); err != nil {
+				return read, err
+			}
+			start = t.offset - read
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return t.offset - start, err
+		}
+
+		// if we encounter EOF before a line delimiter,
+		// ReadBytes() will return the remaining bytes,
+		// so push them back onto the buffer, rewind
+		// our seek position, and wait for further file changes.
+		// we also have to save our dangling byte count in the event
+		// that we want to re-open the file and seek to the end
+		if err == io.EOF {
+			l := len(s)
+
+			t.offset, err = t.file.Seek(-int64(l), io.SeekCurrent)
+			if err != nil {
+				return t.offset - start, err
+			}
+
+			t.reader.Reset(headChecker{t})
+			return t.offset - start, nil
+		}
+
+		t.addHead(s)
+		t.offset += int64(len(s))
+		t.stats.bytes.Add(int64(len(s)))
+		if err := t.addLine(s[:len(s)-1], discarded, lineStart); err != nil {
+			return t.offset - start, err
+		}
+	}
+}
+
+// rotated reports whether the file name refers to another file or to none.
+func (t *Follower) rotated() (bool, error) {
+	fi1, err := t.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	fi2, err := os.Stat(t.filename)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !os.SameFile(fi1, fi2), nil
+}
+
+// truncated reports whether the file is shorter than the read position or its
+// first bytes changed since they were read.
+func (t *Follower) truncated() (bool, error) {
+	fi, err := t.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if t.offset > fi.Size() {
+		return true, nil
+	}
+
+	if len(t.head) == 0 {
+		return false, nil
+	}
+
+	head := make([]byte, len(t.head))
+	n, err := t.file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	return !bytes.Equal(head[:n], t.head), nil
+}
+
+// addHead keeps the bytes read from the file start until fingerprintSize of
+// them are kept. They are the bytes actually consumed, so a file rewritten
+// right after reading is not taken for the one read.
+func (t *Follower) addHead(b []byte) {
+	if n := fingerprintSize - len(t.head); n > 0 {
+		t.head = append(t.head, b[:min(n, len(b))]...)
+	}
+}
+
+// errRewritten is returned by headChecker if the file head changed.
+var errRewritten = errors.New("file rewritten")
+
+// headChecker reads the file and fails with errRewritten if the bytes read from
+// its start changed meanwhile. The check follows the read, so the data of a
+// file rewritten at any moment before is not taken for appended lines.
+type headChecker struct {
+	t *Follower
+}
+
+func (c headChecker) Read(p []byte) (int, error) {
+	n, err := c.t.file.Read(p)
+	if n > 0 {
+		if rewritten, terr := c.t.truncated(); terr != nil {
+			return 0, terr
+		} else if rewritten {
+			return 0, errRewritten
+		}
+	}
+	return n, err
+}
+
+// readHead keeps the leading bytes of the file skipped by seeking to the
+// offset, as they were not read.
+func (t *Follower) readHead() error {
+	t.head = make([]byte, min(t.offset, fingerprintSize))
+	n, err := t.file.ReadAt(t.head, 0)
+	t.head = t.head[:n]
+	if err == io.EOF {
+		err = nil
+	}
+	return err
+}
+
+// rotate keeps reading the rotated file until the new one appears and the old
+// one is idle for RotateGrace, as the writer may reopen its log some time after
+// rotation, e.g. nginx on USR1 signal. Then it switches to the new file.
+func (t *Follower) rotate(eventChan chan fsnotify.Event) error {
+	idle := time.Now()
+	for {
+		n, err := t.readLines()
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			idle = time.Now()
+		}
+
+		if time.Since(idle) >= t.rotateGrace() {
+			_, err := os.Stat(t.filename)
+			if err == nil {
+				break
+			}
+
+			if !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		select {
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		case <-time.After(drainInterval):
+		}
+	}
+
+	if err := t.flushRecord(); err != nil {
+		return err
+	}
+
+	if err := t.rewatch(); err != nil {
+		return err
+	}
+
+	t.pending = false
+	t.stats.rotations.Add(1)
+
+	// events of the old file are not relevant anymore
+	for {
+		select {
+		case <-eventChan:
+		default:
+			return nil
+		}
+	}
+}
+
+func (t *Follower) rotateGrace() time.Duration {
+	if t.config.RotateGrace <= 0 {
+		return DefaultRotateGrace
+	}
+	return t.config.RotateGrace
+}
+
+// watch starts watching the file for changes.
+func (t *Follower) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(t.filename); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	t.watcher = watcher
+	return nil
+}
+
+func (t *Follower) closeWatcher() {
+	if t.watcher != nil {
+		t.watcher.Close()
+	}
+}
+
+// rewatch opens the new file after rotation. If it can not be watched, it is
+// polled instead.
 func (t *Follower) rewatch() error {
-	t.watcher.Remove(t.filename)
+	if t.watcher != nil {
+		t.watcher.Remove(t.filename)
+	}
 	if err := t.reopen(); err != nil {
 		return err
 	}
 
-	t.watcher.Add(t.filename)
+	if t.watcher != nil {
+		if err := t.watcher.Add(t.filename); err != nil {
+			t.fallback(err)
+		}
+	}
 	return nil
 }
 
+func (t *Follower) pollInterval() time.Duration {
+	if t.config.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return t.config.PollInterval
+}
+
+func (t *Follower) pollMaxInterval() time.Duration {
+	if t.config.PollMaxInterval <= 0 {
+		return max(t.pollInterval(), DefaultPollMaxInterval)
+	}
+	return max(t.pollInterval(), t.config.PollMaxInterval)
+}
+
+// truncate starts reading the truncated file from the beginning.
+func (t *Follower) truncate() error {
+	t.stats.truncations.Add(1)
+	if err := t.flushRecord(); err != nil {
+		return err
+	}
+	return t.seek(0, io.SeekStart)
+}
+
 func (t *Follower) reopen() error {
 	if t.file != nil {
 		t.file.Close()
@@ -262,14 +728,27 @@ func (t *Follower) reopen() error {
 		return err
 	}
 
-	t.file = file
-	t.reader = bufio.NewReaderSize(t.file, bufSize)
+	t.setFile(file)
 
 	return nil
 }
 
+// setFile starts reading the file from the beginning.
+func (t *Follower) setFile(file *os.File) {
+	t.file = file
+	t.reader = bufio.NewReaderSize(headChecker{t}, bufSize)
+	t.offset = 0
+	t.delivered.Store(0)
+	t.head = nil
+}
+
 func (t *Follower) close(err error) {
+	if cerr := t.checkpoint(true); err == nil {
+		err = cerr
+	}
+	t.mu.Lock()
 	t.err = err
+	t.mu.Unlock()
 
 	if t.file != nil {
 		t.file.Close()
@@ -278,8 +757,148 @@ func (t *Follower) close(err error) {
 	close(t.lines)
 }
 
-func (t *Follower) sendLine(l []byte, d int) {
-	t.lines <- Line{l[:len(l)-1], d}
+// addLine sends the line, or adds it to the pending record and sends the
+// previous one if it is complete.
+func (t *Follower) addLine(l []byte, d int, start int64) error {
+	if t.assembler == nil {
+		return t.sendLine(l, d, start, t.offset)
+	}
+
+	if !t.assembler.Pending() {
+		t.recordStart = start
+	}
+	record, ok := t.assembler.Add(l)
+	recordStart, end, discarded := t.recordStart, t.recordEnd, t.recordDiscarded
+	t.recordEnd, t.recordTime = t.offset, time.Now()
+	if !ok {
+		t.recordDiscarded += d
+		return nil
+	}
+
+	t.recordStart, t.recordDiscarded = start, d
+	return t.sendLine(record, discarded, recordStart, end)
+}
+
+func (t *Follower) recordPending() bool {
+	return t.assembler != nil && t.assembler.Pending()
+}
+
+// flushRecord sends the pending record.
+func (t *Follower) flushRecord() error {
+	if !t.recordPending() {
+		return nil
+	}
+
+	record, _ := t.assembler.Flush()
+	discarded := t.recordDiscarded
+	t.recordDiscarded = 0
+	return t.sendLine(record, discarded, t.recordStart, t.recordEnd)
+}
+
+// sendLine delivers the line between start and end offsets.
+func (t *Follower) sendLine(l []byte, d int, start, offset int64) error {
+	select {
+	case t.lines <- Line{bytes: l, discarded: d, start: start, offset: offset, source: t.filename}:
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+	t.delivered.Store(offset)
+	t.stats.lines.Add(1)
+	return t.checkpoint(false)
+}
+
+// seekStart seeks to the configured position or resumes from the checkpoint.
+func (t *Follower) seekStart() error {
+	if t.config.Checkpoints != nil {
+		cp, ok, err := t.config.Checkpoints.Load(t.checkpointKey())
+		if err != nil {
+			return err
+		}
+		if ok {
+			offset, ok, err := resumeOffset(t.file, cp)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return t.resumeRotated(cp)
+			}
+			return t.seek(offset, io.SeekStart)
+		}
+	}
+	return t.seek(t.config.Offset, t.config.Whence)
+}
+
+// resumeRotated continues reading the rotated file if the checkpoint belongs to
+// it, otherwise the file is read from the beginning.
+func (t *Follower) resumeRotated(cp Checkpoint) error {
+	name := t.config.RotatedName
+	if name == "" {
+		name = t.filename + ".1"
+	}
+
+	rotated, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return t.seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return err
+	}
+
+	offset, ok, err := resumeOffset(rotated, cp)
+	if err != nil || !ok {
+		rotated.Close()
+		if err != nil {
+			return err
+		}
+		return t.seek(0, io.SeekStart)
+	}
+
+	t.file.Close()
+	t.setFile(rotated)
+	t.pending = true
+	return t.seek(offset, io.SeekStart)
+}
+
+func (t *Follower) seek(offset int64, whence int) error {
+	offset, err := t.file.Seek(offset, whence)
+	if err != nil {
+		return err
+	}
+	t.offset = offset
+	t.delivered.Store(offset)
+	t.reader.Reset(headChecker{t})
+	return t.readHead()
+}
+
+func (t *Follower) checkpointInterval() time.Duration {
+	if t.config.CheckpointInterval <= 0 {
+		return DefaultCheckpointInterval
+	}
+	return t.config.CheckpointInterval
+}
+
+func (t *Follower) checkpointKey() string {
+	if name, err := filepath.Abs(t.filename); err == nil {
+		return name
+	}
+	return t.filename
+}
+
+// checkpoint saves the offset of the last delivered line if it is time to or
+// if forced.
+func (t *Follower) checkpoint(force bool) error {
+	if t.config.Checkpoints == nil || t.file == nil {
+		return nil
+	}
+	if !force && time.Since(t.lastCheckpoint) < t.checkpointInterval() {
+		return nil
+	}
+	cp, err := newCheckpoint(t.file, t.delivered.Load())
+	if err != nil {
+		return err
+	}
+	t.lastCheckpoint = time.Now()
+	return t.config.Checkpoints.Save(t.checkpointKey(), cp)
 }
 
 func (t *Follower) watchFileEvents(eventChan chan fsnotify.Event, errChan chan error) {
@@ -304,6 +923,8 @@ func (t *Follower) watchFileEvents(eventChan chan fsnotify.Event, errChan chan e
 				case err := <-t.watcher.Errors:
 					errChan <- err
 					return
+				case <-t.ctx.Done():
+					return
 				}
 			}
 
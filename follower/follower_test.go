@@ -0,0 +1,421 @@
+package follower
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, name, data string) {
+	t.Helper()
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLines(t *testing.T, f *Follower, n int) []string {
+	t.Helper()
+	var lines []string
+	for len(lines) < n {
+		select {
+		case line, ok := <-f.Lines():
+			if !ok {
+				t.Fatalf("lines closed after %v: %v", lines, f.Err())
+			}
+			lines = append(lines, line.String())
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for lines, got %v", lines)
+		}
+	}
+	return lines
+}
+
+func closeFollower(t *testing.T, f *Follower) {
+	t.Helper()
+	f.Close()
+	for range f.Lines() {
+	}
+	if err := f.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLines(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got lines %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got lines %q, want %q", got, want)
+		}
+	}
+}
+
+func TestCheckpointStore(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	if _, ok, err := store.Load("a.log"); ok || err != nil {
+		t.Fatalf("unexpected checkpoint %v %v", ok, err)
+	}
+	cp := Checkpoint{Offset: 42, Device: 1, Inode: 2, Fingerprint: "abc", FingerprintSize: 3}
+	if err := store.Save("a.log", cp); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("b.log", Checkpoint{Offset: 7}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, ok, err := store.Load("a.log")
+	if err != nil || !ok || loaded != cp {
+		t.Fatalf("loaded %+v %v %v, want %+v", loaded, ok, err, cp)
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(store.Path), "*"))
+	if len(files) != 1 {
+		t.Fatalf("temporary files left: %v", files)
+	}
+}
+
+func TestFollowerResume(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "access.log")
+	store := NewFileCheckpointStore(filepath.Join(dir, "checkpoints.json"))
+	config := Config{Whence: io.SeekStart, Checkpoints: store, CheckpointInterval: time.Hour, RotateGrace: 50 * time.Millisecond}
+
+	appendFile(t, name, "one\ntwo\n")
+	f, err := New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 2), "one", "two")
+	closeFollower(t, f)
+	if f.Offset() != 8 {
+		t.Fatalf("offset %v, want 8", f.Offset())
+	}
+
+	// Lines written while stopped are read after resume
+	appendFile(t, name, "three\n")
+	f, err = New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "three")
+	closeFollower(t, f)
+
+	// Truncated file is read from the beginning
+	if err := os.WriteFile(name, []byte("four\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err = New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "four")
+	closeFollower(t, f)
+
+	// Rest of the rotated file is read before the new one
+	appendFile(t, name, "five\n")
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name, "six\nseven\n")
+	f, err = New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 3), "five", "six", "seven")
+	closeFollower(t, f)
+
+	// Rotated file is not found, the new one is read from the beginning
+	if err := os.Rename(name, name+".2"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name, "eight\n")
+	f, err = New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "eight")
+	closeFollower(t, f)
+}
+
+// modes runs the test with watching and polling for changes.
+func modes(t *testing.T, test func(t *testing.T, poll bool)) {
+	t.Run("watch", func(t *testing.T) { test(t, false) })
+	t.Run("poll", func(t *testing.T) { test(t, true) })
+}
+
+func TestFollowerRotation(t *testing.T) {
+	modes(t, testFollowerRotation)
+}
+
+func testFollowerRotation(t *testing.T, poll bool) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "access.log")
+	config := Config{
+		Whence:       io.SeekStart,
+		Reopen:       true,
+		RotateGrace:  200 * time.Millisecond,
+		Poll:         poll,
+		PollInterval: 10 * time.Millisecond,
+	}
+
+	appendFile(t, name, "one\n")
+	f, err := New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFollower(t, f)
+	if f.Polling() != poll {
+		t.Fatalf("got polling %v, want %v", f.Polling(), poll)
+	}
+	assertLines(t, readLines(t, f, 1), "one")
+
+	// Writer keeps writing the rotated file for a while
+	if err := os.Rename(name, name+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, name, "new\n")
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, name+".1", "two\n")
+	assertLines(t, readLines(t, f, 2), "two", "new")
+
+	// Copytruncate rotation, the file is rewritten beyond the read position
+	appendFile(t, name, "more\n")
+	assertLines(t, readLines(t, f, 1), "more")
+	if err := os.WriteFile(name, []byte("rewritten after truncation\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "rewritten after truncation")
+}
+
+func TestFollowerShutdown(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, name, "\x00\x00one\ntwo\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f, err := New(ctx, name, Config{Whence: io.SeekStart})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 2), "one", "two")
+	if err := os.WriteFile(name, []byte("three\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "three")
+
+	waitStats(t, f, Stats{Bytes: 16, Lines: 3, Truncations: 1, Discarded: 2})
+
+	// Cancelled context stops following and is reported
+	cancel()
+	for range f.Lines() {
+	}
+	if err := f.Err(); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	// Close after the run loop exited and repeated Close do not block
+	f.Close()
+	f.Close()
+
+	if _, err := New(context.Background(), filepath.Join(t.TempDir(), "missing.log"), Config{}); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
+
+func TestFollowerMultiline(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "error.log")
+	appendFile(t, name, "1 first\n  at a\n2 second\n")
+
+	config := Config{
+		Whence: io.SeekStart,
+		Multiline: &Multiline{
+			Start:   regexp.MustCompile(`^\d`),
+			Timeout: 50 * time.Millisecond,
+		},
+	}
+	f, err := New(context.Background(), name, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFollower(t, f)
+
+	line := <-f.Lines()
+	if line.String() != "1 first\n  at a" || line.Offset() != 15 {
+		t.Fatalf("got record %q at %d", line.String(), line.Offset())
+	}
+
+	// The last record is delivered after the timeout
+	start := time.Now()
+	assertLines(t, readLines(t, f, 1), "2 second")
+	if time.Since(start) < 40*time.Millisecond {
+		t.Fatal("record delivered before timeout")
+	}
+	if f.Offset() != 24 {
+		t.Fatalf("got offset %d, want 24", f.Offset())
+	}
+
+	appendFile(t, name, "3 third\n  at b\n  at c\n")
+	line = <-f.Lines()
+	if line.String() != "3 third\n  at b\n  at c" || line.Start() != 24 || line.Offset() != 46 {
+		t.Fatalf("got record %q at %d-%d", line.String(), line.Start(), line.Offset())
+	}
+}
+
+func TestFollowerFallback(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "access.log")
+	appendFile(t, name, "one\n")
+	f, err := New(context.Background(), name, Config{Whence: io.SeekStart, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, readLines(t, f, 1), "one")
+
+	// Watching errors are reported while lines are still delivered
+	f.fallback(errors.New("no space left on device"))
+	if err := f.Err(); !errors.Is(err, ErrPolling) || !f.Polling() {
+		t.Fatalf("got error %v, polling %v", err, f.Polling())
+	}
+	appendFile(t, name, "two\n")
+	assertLines(t, readLines(t, f, 1), "two")
+
+	f.Close()
+	if err := f.Err(); !errors.Is(err, ErrPolling) {
+		t.Fatalf("got error %v, want %v", err, ErrPolling)
+	}
+}
+
+// waitStats waits for the stats as they are updated after a line is received.
+func TestMultiCancel(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "a.log"), "a1\na2\n")
+	appendFile(t, filepath.Join(dir, "b.log"), "b1\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m, err := NewMulti(ctx, dir, Config{Whence: io.SeekStart})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lines are not read, so forwarding them is blocked when cancelled
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-m.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for followers to stop")
+	}
+	if err := m.Err(); err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	m.Close()
+}
+
+func waitStats(t *testing.T, f *Follower, want Stats) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := f.Stats()
+		if stats == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got stats %+v, want %+v", stats, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitFiles(t *testing.T, m *Multi, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := m.Files()
+		if len(got) == len(want) {
+			match := true
+			for i := range got {
+				match = match && got[i] == want[i]
+			}
+			if match {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("followed files %v, want %v", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	modes(t, testMulti)
+}
+
+func testMulti(t *testing.T, poll bool) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	appendFile(t, a, "a1\n")
+	appendFile(t, filepath.Join(dir, ".hidden"), "h1\n")
+
+	config := Config{Whence: io.SeekStart, Poll: poll, PollInterval: 10 * time.Millisecond}
+	m, err := NewMulti(context.Background(), dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	sources := func(n int) []string {
+		var lines []string
+		for len(lines) < n {
+			select {
+			case line, ok := <-m.Lines():
+				if !ok {
+					t.Fatalf("lines closed: %v", m.Err())
+				}
+				lines = append(lines, filepath.Base(line.Source())+":"+line.String())
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout waiting for lines, got %v", lines)
+			}
+		}
+		return lines
+	}
+
+	assertLines(t, sources(1), "a.log:a1")
+
+	// New files are followed from the beginning
+	appendFile(t, b, "b1\n")
+	waitFiles(t, m, a, b)
+	appendFile(t, b, "b2\n")
+	assertLines(t, sources(2), "b.log:b1", "b.log:b2")
+
+	// Removed files are dropped
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	waitFiles(t, m, a)
+
+	// Renamed file is not read again
+	appendFile(t, a, "a2\n")
+	assertLines(t, sources(1), "a.log:a2")
+	if err := os.Rename(a, a+".1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFiles(t, m, a+".1")
+	appendFile(t, a+".1", "a3\n")
+	assertLines(t, sources(1), "a.log.1:a3")
+
+	m.Close()
+	for range m.Lines() {
+	}
+	if err := m.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
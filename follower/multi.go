@@ -0,0 +1,381 @@
+package follower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Multi follows all files matching a glob pattern or in a directory. Files which
+// appear later are followed from the beginning, removed or renamed ones are
+// dropped. Lines of all files are delivered through a single channel, use
+// Line.Source to tell them apart.
+//
+// A file renamed within the pattern, e.g. `access.log` rotated to `access.log.1`
+// when following a directory, is followed further from the same position instead
+// of being read again. Hidden files, i.e. starting with a dot, and directories
+// are skipped. Only the base name of the pattern may contain wildcards.
+//
+// In the polling mode, see Config, the pattern is matched again every
+// PollInterval to pick up new, moved and removed files.
+type Multi struct {
+	ctx       context.Context
+	pattern   string
+	dir       string
+	config    Config
+	lines     chan Line
+	watcher   *fsnotify.Watcher
+	followers map[string]*followed
+	moved     map[fileKey]int64
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	err       error
+	pollErr   error
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewMulti starts following files matching the pattern. If pattern is a
+// directory, all its files are followed. Files existing at start are positioned
+// according to the config. Reopen option is ignored, as new files are picked up
+// anyway. Following stops when the context is done or on Close.
+func NewMulti(ctx context.Context, pattern string, config Config) (*Multi, error) {
+	pattern = filepath.Clean(pattern)
+	if fi, err := os.Stat(pattern); err == nil && fi.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	m := &Multi{
+		ctx:       ctx,
+		pattern:   pattern,
+		dir:       filepath.Dir(pattern),
+		config:    config,
+		lines:     make(chan Line),
+		followers: make(map[string]*followed),
+		moved:     make(map[fileKey]int64),
+		closeCh:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	m.config.Reopen = false
+	if !config.Poll {
+		if err := m.watch(); err != nil {
+			// watching the files fails likely as well
+			m.pollErr = fmt.Errorf("%w: %v", ErrPolling, err)
+			m.config.Poll = true
+		}
+	}
+
+	names, _ := filepath.Glob(pattern)
+	sort.Strings(names)
+	for _, name := range names {
+		m.follow(name, m.config)
+	}
+
+	go m.run()
+	return m, nil
+}
+
+// Lines returns the channel of lines of all followed files. It is closed after
+// Close.
+func (m *Multi) Lines() chan Line {
+	return m.lines
+}
+
+// Err returns the first error of the watcher or any of followed files. If
+// there is none but watching failed and files are polled instead, it is
+// ErrPolling with the reason.
+func (m *Multi) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		return m.pollErr
+	}
+	return m.err
+}
+
+// Files returns names of currently followed files.
+func (m *Multi) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.followers))
+	for name := range m.followers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close stops following all files and waits until they are closed.
+func (m *Multi) Close() {
+	m.closeOnce.Do(func() { close(m.closeCh) })
+	<-m.done
+}
+
+// watch starts watching the directory for changes.
+func (m *Multi) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(m.dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	m.watcher = watcher
+	return nil
+}
+
+func (m *Multi) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// fileKey identifies a file by device and inode numbers.
+type fileKey struct {
+	dev, ino uint64
+}
+
+type followed struct {
+	*Follower
+	key fileKey
+}
+
+func statKey(name string) (fileKey, bool) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileKey{}, false
+	}
+	dev, ino := fileID(fi)
+	return fileKey{dev, ino}, dev != 0 || ino != 0
+}
+
+func (m *Multi) matches(name string) bool {
+	base := filepath.Base(name)
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+	ok, _ := filepath.Match(m.pattern, name)
+	if !ok {
+		return false
+	}
+	fi, err := os.Stat(name)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// follow starts following the file unless it is followed already. A file moved
+// from a followed name continues from the position it was left at.
+func (m *Multi) follow(name string, config Config) {
+	if !m.matches(name) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.followers[name]; ok {
+		return
+	}
+	key, known := statKey(name)
+	if offset, ok := m.moved[key]; known && ok {
+		delete(m.moved, key)
+		config.Checkpoints = nil
+		config.Offset, config.Whence = offset, io.SeekStart
+	}
+	f, err := New(m.ctx, name, config)
+	if err != nil {
+		if !os.IsNotExist(err) && m.err == nil {
+			m.err = err
+		}
+		return
+	}
+	m.followers[name] = &followed{Follower: f, key: key}
+	m.wg.Add(1)
+	go m.forward(name, f)
+}
+
+// unfollow stops following the file. The position of a moved file is kept in
+// case it appears under a new name.
+func (m *Multi) unfollow(name string, moved bool) {
+	m.mu.Lock()
+	f, ok := m.followers[name]
+	delete(m.followers, name)
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	f.Close()
+	if moved {
+		m.mu.Lock()
+		m.moved[f.key] = f.Offset()
+		m.mu.Unlock()
+	}
+}
+
+func (m *Multi) forward(name string, f *Follower) {
+	defer m.wg.Done()
+	for line := range f.Lines() {
+		select {
+		case m.lines <- line:
+		case <-m.closeCh:
+			f.Close()
+		}
+	}
+	err := f.Err()
+	if errors.Is(err, ErrPolling) {
+		m.mu.Lock()
+		if m.pollErr == nil {
+			m.pollErr = err
+		}
+		m.mu.Unlock()
+		err = nil
+	}
+	if err != nil {
+		m.setErr(err)
+	}
+	m.mu.Lock()
+	if current, ok := m.followers[name]; ok && current.Follower == f {
+		delete(m.followers, name)
+		// the follower stopped as the file was moved before we noticed
+		if key, ok := statKey(name); err == nil && (!ok || key != current.key) && m.matched(current.key) {
+			m.moved[current.key] = f.Offset()
+		}
+	}
+	m.mu.Unlock()
+}
+
+func (m *Multi) run() {
+	defer close(m.done)
+	defer close(m.lines)
+	defer m.wg.Wait()
+	// unblock forwarding of lines nobody reads any more on every exit
+	defer m.closeOnce.Do(func() { close(m.closeCh) })
+
+	// files created after start are read from the beginning
+	created := m.config
+	created.Offset, created.Whence = 0, io.SeekStart
+
+	if m.watcher == nil {
+		m.poll(created)
+		return
+	}
+	defer m.watcher.Close()
+
+	for {
+		select {
+		case evt, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Clean(evt.Name)
+			switch {
+			case evt.Op&fsnotify.Create != 0:
+				m.follow(name, created)
+			case evt.Op&fsnotify.Rename != 0:
+				m.unfollow(name, true)
+			case evt.Op&fsnotify.Remove != 0:
+				m.unfollow(name, false)
+			}
+
+		case err, ok := <-m.watcher.Errors:
+			if ok {
+				m.setErr(err)
+			}
+			m.closeAll()
+			return
+
+		case <-m.closeCh:
+			m.closeAll()
+			return
+
+		case <-m.ctx.Done():
+			m.setErr(m.ctx.Err())
+			m.closeAll()
+			return
+		}
+	}
+}
+
+// poll matches the pattern periodically until closed.
+func (m *Multi) poll(created Config) {
+	interval := m.config.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.scan(created)
+
+		case <-m.closeCh:
+			m.closeAll()
+			return
+
+		case <-m.ctx.Done():
+			m.setErr(m.ctx.Err())
+			m.closeAll()
+			return
+		}
+	}
+}
+
+// scan drops followed files which were moved or removed and follows the new
+// ones. The pattern is matched first, so a file moved in between is not taken
+// for a new one.
+func (m *Multi) scan(config Config) {
+	names, _ := filepath.Glob(m.pattern)
+	sort.Strings(names)
+
+	for _, name := range m.Files() {
+		m.mu.Lock()
+		f, ok := m.followers[name]
+		m.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if key, ok := statKey(name); !ok || key != f.key {
+			m.unfollow(name, m.matched(f.key))
+		}
+	}
+
+	for _, name := range names {
+		m.follow(name, config)
+	}
+}
+
+// matched reports whether a file matching the pattern has the key, i.e. the
+// file was moved within the pattern.
+func (m *Multi) matched(key fileKey) bool {
+	names, _ := filepath.Glob(m.pattern)
+	for _, name := range names {
+		if k, ok := statKey(name); ok && k == key && m.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Multi) closeAll() {
+	m.mu.Lock()
+	followers := m.followers
+	m.followers = make(map[string]*followed)
+	m.mu.Unlock()
+	for _, f := range followers {
+		f.Close()
+	}
+}
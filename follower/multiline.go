@@ -0,0 +1,99 @@
+package follower
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultMultilineMaxBytes is the record size limit if none is set.
+const DefaultMultilineMaxBytes = 1 << 20
+
+// DefaultMultilineTimeout is the time a pending record is flushed after if no
+// new line is written and none is set.
+const DefaultMultilineTimeout = time.Second
+
+// Multiline configures grouping of physical lines into records, e.g. error logs
+// with stack traces. A line matching Start begins a new record, others are
+// appended to the previous one. If Start is nil, lines matching Continuation
+// are appended and the others begin a new record. Lines of a record are joined
+// with "\n".
+//
+// Records longer than MaxBytes are truncated. When following a file, a pending
+// record is delivered after Timeout without new lines, as there is no other
+// way to tell the last record is complete.
+type Multiline struct {
+	Start        *regexp.Regexp
+	Continuation *regexp.Regexp
+	MaxBytes     int
+	Timeout      time.Duration
+}
+
+func (m *Multiline) maxBytes() int {
+	if m.MaxBytes <= 0 {
+		return DefaultMultilineMaxBytes
+	}
+	return m.MaxBytes
+}
+
+func (m *Multiline) timeout() time.Duration {
+	if m.Timeout <= 0 {
+		return DefaultMultilineTimeout
+	}
+	return m.Timeout
+}
+
+// starts reports whether the line begins a new record.
+func (m *Multiline) starts(line []byte) bool {
+	switch {
+	case m.Start != nil:
+		return m.Start.Match(line)
+	case m.Continuation != nil:
+		return !m.Continuation.Match(line)
+	}
+	return true
+}
+
+// Assembler groups lines into records according to Multiline config. It is not
+// safe for concurrent use.
+type Assembler struct {
+	config *Multiline
+	record []byte
+}
+
+// NewAssembler creates an Assembler with the config.
+func NewAssembler(config *Multiline) *Assembler {
+	return &Assembler{config: config}
+}
+
+// Add adds the line without the line break. If the line begins a new record,
+// the previous one is returned.
+func (a *Assembler) Add(line []byte) ([]byte, bool) {
+	if a.record != nil && !a.config.starts(line) {
+		a.append('\n')
+		a.append(line...)
+		return nil, false
+	}
+	record, ok := a.Flush()
+	a.record = make([]byte, 0, len(line))
+	a.append(line...)
+	return record, ok
+}
+
+func (a *Assembler) append(b ...byte) {
+	if n := a.config.maxBytes() - len(a.record); len(b) > n {
+		b = b[:max(n, 0)]
+	}
+	a.record = append(a.record, b...)
+}
+
+// Flush returns the pending record, if any.
+func (a *Assembler) Flush() ([]byte, bool) {
+	record := a.record
+	a.record = nil
+	return record, record != nil
+}
+
+// Pending reports whether a record is not returned yet.
+func (a *Assembler) Pending() bool {
+	return a.record != nil
+}
@@ -1,15 +1,18 @@
 module github.com/dreamsxin/gonx
 
-go 1.21
+go 1.22
 
 require (
 	github.com/fsnotify/fsnotify v1.8.0
+	github.com/klauspost/compress v1.18.0
+	github.com/oschwald/maxminddb-golang v1.13.1
 	github.com/smartystreets/goconvey v1.7.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/smartystreets/assertions v1.2.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
 )
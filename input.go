@@ -0,0 +1,254 @@
+package gonx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dreamsxin/gonx/follower"
+)
+
+// Line is a raw log line along with its position in the input.
+type Line struct {
+	// Source is the name of the file line was read from, empty if unknown.
+	Source string
+	// Number is the line number in the Source starting from 1. It is the first
+	// line of a multi-line record.
+	Number int64
+	// Offset is the byte offset of the line start in the Source, in the
+	// decompressed data for compressed files.
+	Offset int64
+	Text   string
+}
+
+// Input is the interface of log lines source for MapReduceInput.
+//
+// ReadLines should send all the lines to the given channel and return when
+// there are no more lines to read. It must not close the channel.
+type Input interface {
+	ReadLines(lines chan<- Line) error
+}
+
+// Tagger is an optional interface of Input to tag each parsed Entry with the
+// line position data.
+type Tagger interface {
+	Tag(entry *Entry, line Line)
+}
+
+// Position returns the position of the line.
+func (l Line) Position() *Position {
+	return &Position{Source: l.Source, Line: l.Number, Offset: l.Offset, Raw: l.Text}
+}
+
+// ReaderInput implements the Input interface for a single io.Reader. Compressed
+// data is decompressed transparently. Lines are read according to LineConfig and
+// grouped into records if Multiline is set. Entries get the line Position if
+// Positions is set.
+type ReaderInput struct {
+	Source     string
+	LineConfig LineConfig
+	Multiline  *follower.Multiline
+	Positions  bool
+	reader     io.Reader
+}
+
+// NewReaderInput creates an Input reading lines from the given reader. The source
+// name is used for the lines position only.
+func NewReaderInput(reader io.Reader, source string) *ReaderInput {
+	return &ReaderInput{Source: source, reader: reader}
+}
+
+// ReadLines implements the Input interface.
+func (i *ReaderInput) ReadLines(lines chan<- Line) error {
+	reader, err := Decompress(i.reader)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return readLines(reader, i.Source, &i.LineConfig, i.Multiline, lines)
+}
+
+// Tag implements the Tagger interface.
+func (i *ReaderInput) Tag(entry *Entry, line Line) {
+	if i.Positions {
+		entry.Position = line.Position()
+	}
+}
+
+func readLines(file io.Reader, source string, config *LineConfig, multiline *follower.Multiline, lines chan<- Line) error {
+	reader := bufio.NewReader(file)
+	var assembler *follower.Assembler
+	if multiline != nil {
+		assembler = follower.NewAssembler(multiline)
+	}
+	// number and offset of the pending record first line
+	var number, offset, start, startOffset int64
+	flush := func() {
+		if assembler == nil {
+			return
+		}
+		if record, ok := assembler.Flush(); ok {
+			lines <- Line{Source: source, Number: start, Offset: startOffset, Text: string(record)}
+		}
+	}
+	for {
+		text, n, err := config.readLine(reader)
+		if err == io.EOF {
+			flush()
+			return nil
+		}
+		number++
+		lineOffset := offset
+		offset += n
+		if err == ErrLineTooLong {
+			err = fmt.Errorf("%v:%d: %w", source, number, err)
+			if config.Policy == LineSkip {
+				handleError(err)
+				continue
+			}
+		}
+		if err != nil {
+			flush()
+			return err
+		}
+
+		if assembler == nil {
+			lines <- Line{Source: source, Number: number, Offset: lineOffset, Text: text}
+			continue
+		}
+		if !assembler.Pending() {
+			start, startOffset = number, lineOffset
+		}
+		if record, ok := assembler.Add([]byte(text)); ok {
+			lines <- Line{Source: source, Number: start, Offset: startOffset, Text: string(record)}
+			start, startOffset = number, lineOffset
+		}
+	}
+}
+
+// Suggested names of fields for Files input to tag entries with.
+const (
+	DefaultSourceField = "source"
+	DefaultLineField   = "line"
+)
+
+// Files implements the Input interface for a list of log files.
+//
+// Files are read one by one in the given order, or all at once if Parallel is
+// set. Compressed files are decompressed transparently by Decompressor. If
+// SourceField or LineField are set, e.g. to DefaultSourceField and
+// DefaultLineField, each Entry is tagged with the file name and line number in
+// these fields. Prefer Positions to keep them apart from the log fields, so
+// reducers are not affected. Lines are read according to
+// LineConfig and grouped into records if Multiline is set. Entries get the line
+// Position if Positions is set.
+type Files struct {
+	Names        []string
+	Parallel     bool
+	SourceField  string
+	LineField    string
+	Decompressor Decompressor
+	LineConfig   LineConfig
+	Multiline    *follower.Multiline
+	Positions    bool
+}
+
+// NewFiles creates an Input for the given files.
+func NewFiles(names ...string) *Files {
+	return &Files{Names: names}
+}
+
+// NewGlob creates an Input for files matching the pattern. Files are ordered by
+// SortRotated, so logs are read from the oldest to the newest one.
+func NewGlob(pattern string) (*Files, error) {
+	names, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	SortRotated(names)
+	return NewFiles(names...), nil
+}
+
+// ReadLines implements the Input interface.
+func (f *Files) ReadLines(lines chan<- Line) error {
+	if !f.Parallel {
+		for _, name := range f.Names {
+			if err := f.readFile(name, lines); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, name := range f.Names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := f.readFile(name, lines); err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(name)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+func (f *Files) readFile(name string, lines chan<- Line) error {
+	file, err := f.Decompressor.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return readLines(file, name, &f.LineConfig, f.Multiline, lines)
+}
+
+// Tag implements the Tagger interface.
+func (f *Files) Tag(entry *Entry, line Line) {
+	if f.SourceField != "" {
+		entry.SetField(f.SourceField, line.Source)
+	}
+	if f.LineField != "" {
+		entry.SetField(f.LineField, line.Number)
+	}
+	if f.Positions {
+		entry.Position = line.Position()
+	}
+}
+
+var rotatedRe = regexp.MustCompile(`^(.*?)(?:\.(\d+))?((?:\.(?:gz|bz2|zst))?)$`)
+
+// SortRotated sorts file names in log rotation order. Files with the same base
+// name are ordered by descending rotation number, so `access.log.2.gz` goes
+// before `access.log.1` and `access.log` is the last one.
+func SortRotated(names []string) {
+	type rotated struct {
+		base   string
+		number int
+	}
+	keys := make(map[string]rotated, len(names))
+	for _, name := range names {
+		m := rotatedRe.FindStringSubmatch(name)
+		key := rotated{base: m[1], number: -1}
+		if m[2] != "" {
+			key.number, _ = strconv.Atoi(m[2])
+		}
+		keys[name] = key
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		a, b := keys[names[i]], keys[names[j]]
+		if a.base != b.base {
+			return a.base < b.base
+		}
+		return a.number > b.number
+	})
+}
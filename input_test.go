@@ -0,0 +1,149 @@
+package gonx
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dreamsxin/gonx/follower"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInput(t *testing.T) {
+	Convey("Test multi-file input", t, func() {
+		dir := t.TempDir()
+		write := func(name, content string) string {
+			path := filepath.Join(dir, name)
+			So(os.WriteFile(path, []byte(content), 0644), ShouldBeNil)
+			return path
+		}
+		current := write("access.log", "3 c\n4 d\n")
+		rotated := write("access.log.1", "2 b\n")
+		oldest := write("access.log.10", "1 a\n")
+		parser := NewParser("$id $name")
+
+		Convey("Sort rotated files", func() {
+			names := []string{"b.log", "access.log", "access.log.2.gz", "access.log.10.gz", "access.log.1"}
+			SortRotated(names)
+			So(names, ShouldResemble, []string{"access.log.10.gz", "access.log.2.gz", "access.log.1", "access.log", "b.log"})
+		})
+
+		Convey("Read glob in rotation order", func() {
+			input, err := NewGlob(filepath.Join(dir, "access.log*"))
+			So(err, ShouldBeNil)
+			So(input.Names, ShouldResemble, []string{oldest, rotated, current})
+
+			reader := NewInputReader(input, parser)
+			var ids []string
+			for {
+				entry, err := reader.Read()
+				if err != nil {
+					break
+				}
+				id, _ := entry.StringField("id")
+				ids = append(ids, id)
+			}
+			// Lines order is not guaranteed by concurrent mappers
+			So(ids, ShouldHaveLength, 4)
+			So(ids, ShouldContain, "1")
+			So(ids, ShouldContain, "4")
+		})
+
+		Convey("Tag entries with position", func() {
+			input := NewFiles(rotated, current)
+			input.Parallel = true
+			output := MapReduceInput(input, parser, new(ReadAll))
+			for entry := range output {
+				// Not tagged by default
+				So(entry.Fields, ShouldHaveLength, 2)
+			}
+
+			input.SourceField, input.LineField = DefaultSourceField, DefaultLineField
+			output = MapReduceInput(input, parser, new(ReadAll))
+			positions := make(map[string]string)
+			for entry := range output {
+				name, _ := entry.StringField("name")
+				positions[name] = entry.FieldsHash([]string{DefaultSourceField, DefaultLineField})
+			}
+			So(positions, ShouldResemble, map[string]string{
+				"b": "'source'=" + rotated + ";'line'=1",
+				"c": "'source'=" + current + ";'line'=1",
+				"d": "'source'=" + current + ";'line'=2",
+			})
+		})
+
+		Convey("Group multi-line records", func() {
+			trace := write("error.log", "  orphan\n1 first\n  at a\n  at b\n2 second\n3 third\n  at c\n")
+			read := func(multiline *follower.Multiline) []Line {
+				input := NewFiles(trace)
+				input.Multiline = multiline
+				lines := make(chan Line)
+				errs := make(chan error, 1)
+				go func() {
+					errs <- input.ReadLines(lines)
+					close(lines)
+				}()
+				var result []Line
+				for line := range lines {
+					result = append(result, line)
+				}
+				So(<-errs, ShouldBeNil)
+				return result
+			}
+
+			records := read(&follower.Multiline{Start: regexp.MustCompile(`^\d`)})
+			So(records, ShouldResemble, []Line{
+				{Source: trace, Number: 1, Offset: 0, Text: "  orphan"},
+				{Source: trace, Number: 2, Offset: 9, Text: "1 first\n  at a\n  at b"},
+				{Source: trace, Number: 5, Offset: 31, Text: "2 second"},
+				{Source: trace, Number: 6, Offset: 40, Text: "3 third\n  at c"},
+			})
+
+			continued := read(&follower.Multiline{Continuation: regexp.MustCompile(`^\s`), MaxBytes: 12})
+			So(continued, ShouldHaveLength, 4)
+			So(continued[1].Text, ShouldEqual, "1 first\n  at")
+			So(continued[3].Text, ShouldEqual, "3 third\n  at")
+			So(strings.Count(continued[0].Text, "\n"), ShouldEqual, 0)
+		})
+
+		Convey("Keep entry positions", func() {
+			input := NewFiles(current)
+			input.Positions = true
+			output := MapReduceInput(input, parser, new(ReadAll))
+
+			positions := make(map[string]Position)
+			for entry := range output {
+				name, _ := entry.StringField("name")
+				positions[name] = *entry.Position
+				data, err := json.Marshal(entry)
+				So(err, ShouldBeNil)
+				So(string(data), ShouldNotContainSubstring, "Raw")
+			}
+			So(positions, ShouldResemble, map[string]Position{
+				"c": {Source: current, Line: 1, Offset: 0, Raw: "3 c"},
+				"d": {Source: current, Line: 2, Offset: 4, Raw: "4 d"},
+			})
+			So(positions["d"].String(), ShouldEqual, current+":2")
+
+			// Positions are not kept by default
+			reader := NewParserReader(strings.NewReader("1 a\n"), parser)
+			entry, err := reader.Read()
+			So(err, ShouldBeNil)
+			So(entry.Position, ShouldBeNil)
+		})
+
+		Convey("Feed one reducer", func() {
+			input := NewFiles(oldest, rotated, current)
+			output := MapReduceInput(input, parser, &Sum{map[string]string{"id": "id"}})
+
+			result := <-output
+			value, err := result.FloatField("id")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 1+2+3+4)
+		})
+	})
+}
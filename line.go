@@ -0,0 +1,124 @@
+package gonx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// DefaultMaxLineLength is the line length limit if none is set.
+const DefaultMaxLineLength = 1 << 20
+
+// ErrLineTooLong is reported for lines longer than LineConfig.MaxLength.
+var ErrLineTooLong = errors.New("line too long")
+
+// LinePolicy is the handling of lines longer than the limit.
+type LinePolicy int
+
+const (
+	// LineTruncate cuts long lines to the limit.
+	LineTruncate LinePolicy = iota
+	// LineSkip drops long lines reporting ErrLineTooLong to ErrorHandler.
+	LineSkip
+	// LineFail stops reading with ErrLineTooLong.
+	LineFail
+)
+
+// LineConfig controls reading of log lines. Lines longer than MaxLength bytes,
+// DefaultMaxLineLength if zero, are handled according to Policy. A negative
+// MaxLength disables the limit.
+//
+// NUL bytes, e.g. left in a preallocated or truncated file, are discarded and
+// do not count to the line length. Line endings are "\n" or "\r\n". Invalid
+// UTF-8 sequences are replaced with U+FFFD unless KeepInvalidUTF8 is set, e.g.
+// for logs in a legacy encoding.
+type LineConfig struct {
+	MaxLength       int
+	Policy          LinePolicy
+	KeepInvalidUTF8 bool
+}
+
+func (c *LineConfig) maxLength() int {
+	if c.MaxLength == 0 {
+		return DefaultMaxLineLength
+	}
+	return c.MaxLength
+}
+
+// readLine reads the next line and returns it with the number of bytes read.
+// For a long line ErrLineTooLong is returned unless it is truncated, the line
+// is consumed anyway.
+func (c *LineConfig) readLine(reader *bufio.Reader) (string, int64, error) {
+	limit := c.maxLength()
+	var (
+		line    []byte
+		tooLong bool
+		n       int64
+	)
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		n += int64(len(chunk))
+		if err != nil && err != bufio.ErrBufferFull && err != io.EOF {
+			return "", n, err
+		}
+		eol := err == nil
+		if eol {
+			chunk = chunk[:len(chunk)-1]
+		}
+		switch {
+		case tooLong:
+		case line == nil && eol && bytes.IndexByte(chunk, 0) < 0:
+			// the whole line is in the buffer, avoid copying
+			line = chunk
+		default:
+			line = appendNonNUL(line, chunk)
+			// keep room for "\r" of the line ending split across chunks
+			if limit >= 0 && len(line) > limit+1 {
+				line, tooLong = line[:limit+1], true
+			}
+		}
+		if eol {
+			break
+		}
+		if err == io.EOF {
+			if len(line) == 0 && !tooLong {
+				return "", n, io.EOF
+			}
+			break
+		}
+	}
+
+	if !tooLong {
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+	}
+	if tooLong || limit >= 0 && len(line) > limit {
+		if c.Policy != LineTruncate {
+			return "", n, ErrLineTooLong
+		}
+		line = line[:limit]
+	}
+	if !c.KeepInvalidUTF8 && !utf8.Valid(line) {
+		line = bytes.ToValidUTF8(line, []byte("�"))
+	}
+	return string(line), n, nil
+}
+
+// appendNonNUL appends the chunk without NUL bytes.
+func appendNonNUL(line, chunk []byte) []byte {
+	for {
+		i := bytes.IndexByte(chunk, 0)
+		if i < 0 {
+			return append(line, chunk...)
+		}
+		line = append(line, chunk[:i]...)
+		chunk = chunk[i+1:]
+	}
+}
+
+// readLine reads the next line with the default LineConfig.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, _, err := new(LineConfig).readLine(reader)
+	return line, err
+}
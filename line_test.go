@@ -0,0 +1,91 @@
+package gonx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLineConfig(t *testing.T) {
+	Convey("Test reading lines", t, func() {
+		readAll := func(config *LineConfig, data string) ([]string, error) {
+			reader := bufio.NewReaderSize(strings.NewReader(data), 16)
+			var lines []string
+			for {
+				line, _, err := config.readLine(reader)
+				if err == io.EOF {
+					return lines, nil
+				}
+				if err != nil {
+					return lines, err
+				}
+				lines = append(lines, line)
+			}
+		}
+
+		Convey("Normalize line endings and drop NUL bytes", func() {
+			lines, err := readAll(&LineConfig{}, "one\r\n\x00\x00\x00two\n\r\nthree")
+			So(err, ShouldBeNil)
+			So(lines, ShouldResemble, []string{"one", "two", "", "three"})
+
+			// NUL run without line ending is not a line
+			lines, err = readAll(&LineConfig{}, "one\n"+strings.Repeat("\x00", 100))
+			So(err, ShouldBeNil)
+			So(lines, ShouldResemble, []string{"one"})
+		})
+
+		Convey("Replace invalid UTF-8", func() {
+			lines, _ := readAll(&LineConfig{}, "caf\xe9 ok\n")
+			So(lines, ShouldResemble, []string{"caf� ok"})
+
+			lines, _ = readAll(&LineConfig{KeepInvalidUTF8: true}, "caf\xe9 ok\n")
+			So(lines, ShouldResemble, []string{"caf\xe9 ok"})
+		})
+
+		data := "short\n" + strings.Repeat("x", 40) + "\nexact 10\r\n"
+
+		Convey("Truncate long lines", func() {
+			lines, err := readAll(&LineConfig{MaxLength: 10}, data)
+			So(err, ShouldBeNil)
+			So(lines, ShouldResemble, []string{"short", "xxxxxxxxxx", "exact 10"})
+
+			lines, err = readAll(&LineConfig{MaxLength: -1}, data)
+			So(err, ShouldBeNil)
+			So(lines[1], ShouldHaveLength, 40)
+		})
+
+		Convey("Skip long lines", func() {
+			var reported []error
+			ErrorHandler = func(err error) { reported = append(reported, err) }
+			defer func() { ErrorHandler = nil }()
+
+			input := NewReaderInput(strings.NewReader(data), "test.log")
+			input.LineConfig = LineConfig{MaxLength: 10, Policy: LineSkip}
+			lines := make(chan Line, 10)
+			So(input.ReadLines(lines), ShouldBeNil)
+			close(lines)
+
+			var numbers []int64
+			for line := range lines {
+				numbers = append(numbers, line.Number)
+			}
+			So(numbers, ShouldResemble, []int64{1, 3})
+			So(reported, ShouldHaveLength, 1)
+			So(errors.Is(reported[0], ErrLineTooLong), ShouldBeTrue)
+			So(reported[0].Error(), ShouldStartWith, "test.log:2:")
+		})
+
+		Convey("Fail on long lines", func() {
+			input := NewReaderInput(strings.NewReader(data), "test.log")
+			input.LineConfig = LineConfig{MaxLength: 10, Policy: LineFail}
+			lines := make(chan Line, 10)
+			err := input.ReadLines(lines)
+			So(errors.Is(err, ErrLineTooLong), ShouldBeTrue)
+			So(lines, ShouldHaveLength, 1)
+		})
+	})
+}
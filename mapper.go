@@ -0,0 +1,78 @@
+package gonx
+
+// Mapper interface for Entries transformation.
+//
+// Map method should accept *Entry and return transformed *Entry, it could be
+// the same entry modified in place. Return nil to drop the entry.
+//
+// Mappers implement Reducer too, so they can be placed in NewChain along with
+// filters, where they are applied in the given order before reducers. There
+// the entry is copied before the first mapper, as the original one may be read
+// by other reducers at the same time.
+type Mapper interface {
+	Reducer
+	Map(*Entry) *Entry
+}
+
+// reduceMapper goes through input and writes transformed entries to the output.
+func reduceMapper(m Mapper, input chan *Entry, output chan *Entry) {
+	for entry := range input {
+		if mapped := m.Map(entry); mapped != nil {
+			output <- mapped
+		}
+	}
+	close(output)
+}
+
+// mapperFilter adapts a Mapper to be a Chain stage along with filters.
+type mapperFilter struct {
+	Mapper
+}
+
+func (m mapperFilter) Filter(entry *Entry) *Entry {
+	return m.Map(entry)
+}
+
+// MapperFunc implements the Mapper interface with custom transform function.
+type MapperFunc func(*Entry) *Entry
+
+// Map applies the function to the entry.
+func (f MapperFunc) Map(entry *Entry) *Entry {
+	if f == nil {
+		return entry
+	}
+	return f(entry)
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (f MapperFunc) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(f, input, output)
+}
+
+// MapperParser implements the StringParser interface to transform entries right
+// after parsing. Used with MapReduce it applies mappers in the concurrent mapper
+// goroutines.
+type MapperParser struct {
+	parser  StringParser
+	mappers []Mapper
+}
+
+// NewMapperParser creates a parser applying mappers in order to each parsed entry.
+func NewMapperParser(parser StringParser, mappers ...Mapper) *MapperParser {
+	return &MapperParser{parser: parser, mappers: mappers}
+}
+
+// ParseString parses the line and transforms the entry. Dropped entries are
+// returned as nil without an error, see StringParser.
+func (p *MapperParser) ParseString(line string) (entry *Entry, err error) {
+	entry, err = p.parser.ParseString(line)
+	if err != nil {
+		return
+	}
+	for _, m := range p.mappers {
+		if entry = m.Map(entry); entry == nil {
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package gonx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Replace implements the Mapper interface to replace matches of the Regexp in the
+// field value with the Replacement, see regexp.Regexp.ReplaceAllString. The result
+// is stored in Target field, or replaces the original value if Target is empty.
+type Replace struct {
+	Field       string
+	Regexp      *regexp.Regexp
+	Replacement string
+	Target      string
+}
+
+// Map replaces the field value.
+func (m *Replace) Map(entry *Entry) *Entry {
+	val, err := entry.StringField(m.Field)
+	if err != nil {
+		return entry
+	}
+	entry.SetField(target(m.Target, m.Field), m.Regexp.ReplaceAllString(val, m.Replacement))
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Replace) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Lowercase implements the Mapper interface to lowercase string values of the
+// fields, e.g. hosts.
+type Lowercase struct {
+	Fields []string
+}
+
+// Map lowercases the fields values.
+func (m *Lowercase) Map(entry *Entry) *Entry {
+	for _, name := range m.Fields {
+		if val, err := entry.StringField(name); err == nil {
+			entry.SetField(name, strings.ToLower(val))
+		}
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Lowercase) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Rename implements the Mapper interface to rename fields. Fields maps old names
+// to the new ones.
+type Rename struct {
+	Fields map[string]string
+}
+
+// Map renames the fields.
+func (m *Rename) Map(entry *Entry) *Entry {
+	for from, to := range m.Fields {
+		if val, ok := entry.Fields[from]; ok {
+			delete(entry.Fields, from)
+			entry.SetField(to, val)
+		}
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Rename) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Drop implements the Mapper interface to remove fields from entries.
+type Drop struct {
+	Fields []string
+}
+
+// Map removes the fields.
+func (m *Drop) Map(entry *Entry) *Entry {
+	for _, name := range m.Fields {
+		delete(entry.Fields, name)
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Drop) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Copy implements the Mapper interface to copy fields values. Fields maps source
+// names to the target ones.
+type Copy struct {
+	Fields map[string]string
+}
+
+// Map copies the fields.
+func (m *Copy) Map(entry *Entry) *Entry {
+	for from, to := range m.Fields {
+		if val, ok := entry.Fields[from]; ok {
+			entry.SetField(to, val)
+		}
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Copy) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Arithmetic implements the Mapper interface to compute a numeric field as the
+// Field value and the Operand with the Op, one of "+", "-", "*" and "/". E.g.
+// bytes_kb is computed by
+//
+//	&Arithmetic{Target: "bytes_kb", Field: "body_bytes_sent", Op: "/", Operand: 1024}
+//
+// The result is stored in Target field, or replaces the original value if Target
+// is empty. Entries with missing or non-numeric field are left untouched.
+type Arithmetic struct {
+	Target  string
+	Field   string
+	Op      string
+	Operand float64
+}
+
+// Map computes the field.
+func (m *Arithmetic) Map(entry *Entry) *Entry {
+	val, err := entry.FloatField(m.Field)
+	if err != nil {
+		return entry
+	}
+	switch m.Op {
+	case "+":
+		val += m.Operand
+	case "-":
+		val -= m.Operand
+	case "*":
+		val *= m.Operand
+	case "/":
+		val /= m.Operand
+	default:
+		handleError(fmt.Errorf("unknown arithmetic operator '%v'", m.Op))
+		return entry
+	}
+	entry.SetFloatField(target(m.Target, m.Field), val)
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Arithmetic) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// target returns the target field name, which defaults to the source field.
+func target(name, field string) string {
+	if name == "" {
+		return field
+	}
+	return name
+}
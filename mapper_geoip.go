@@ -0,0 +1,224 @@
+package gonx
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoDB is a local database in MaxMind DB format, e.g. GeoLite2 City, Country
+// or ASN. It is safe for concurrent use.
+type GeoDB struct {
+	reader *maxminddb.Reader
+}
+
+// OpenGeoDB opens the MMDB database file.
+func OpenGeoDB(filename string) (*GeoDB, error) {
+	reader, err := maxminddb.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoDB{reader: reader}, nil
+}
+
+// NewGeoDB creates a database from the MMDB data.
+func NewGeoDB(data []byte) (*GeoDB, error) {
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return &GeoDB{reader: reader}, nil
+}
+
+// Close releases the database resources.
+func (db *GeoDB) Close() error {
+	return db.reader.Close()
+}
+
+// geoRecord is a union of City, Country and ASN databases records.
+type geoRecord struct {
+	Continent struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+	ASN            uint64 `maxminddb:"autonomous_system_number"`
+	ASOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// GeoInfo is the geo location and network of an IP address. Fields unknown to
+// the databases are empty.
+type GeoInfo struct {
+	Continent      string
+	CountryCode    string
+	Country        string
+	Region         string
+	City           string
+	Latitude       *float64
+	Longitude      *float64
+	ASN            uint64
+	ASOrganization string
+}
+
+// Lookup merges the address data found in the database into the info. Names are
+// taken in the given language. It returns false if the address is not found.
+func (db *GeoDB) Lookup(addr netip.Addr, language string, info *GeoInfo) (bool, error) {
+	var record geoRecord
+	_, found, err := db.reader.LookupNetwork(net.IP(addr.AsSlice()), &record)
+	if err != nil || !found {
+		return false, err
+	}
+	name := func(names map[string]string) string {
+		if name, ok := names[language]; ok {
+			return name
+		}
+		return names["en"]
+	}
+	set := func(dst *string, value string) {
+		if value != "" {
+			*dst = value
+		}
+	}
+	set(&info.Continent, record.Continent.Code)
+	set(&info.CountryCode, record.Country.ISOCode)
+	set(&info.Country, name(record.Country.Names))
+	if len(record.Subdivisions) > 0 {
+		set(&info.Region, name(record.Subdivisions[0].Names))
+	}
+	set(&info.City, name(record.City.Names))
+	if record.Location.Latitude != nil && record.Location.Longitude != nil {
+		info.Latitude, info.Longitude = record.Location.Latitude, record.Location.Longitude
+	}
+	if record.ASN != 0 {
+		info.ASN = record.ASN
+	}
+	set(&info.ASOrganization, record.ASOrganization)
+	return true, nil
+}
+
+// SetFields sets the geo fields to the entry with given name prefix. Location and
+// ASN are set only if known, so they could be used by numeric filters.
+func (info *GeoInfo) SetFields(entry *Entry, prefix string) {
+	entry.SetField(prefix+"continent", info.Continent)
+	entry.SetField(prefix+"country_code", info.CountryCode)
+	entry.SetField(prefix+"country", info.Country)
+	entry.SetField(prefix+"region", info.Region)
+	entry.SetField(prefix+"city", info.City)
+	if info.Latitude != nil {
+		entry.SetFloatField(prefix+"latitude", *info.Latitude)
+		entry.SetFloatField(prefix+"longitude", *info.Longitude)
+	}
+	if info.ASN != 0 {
+		entry.SetUintField(prefix+"asn", info.ASN)
+		entry.SetField(prefix+"as_org", info.ASOrganization)
+	}
+}
+
+// DefaultGeoPrefix is the prefix of flat fields set by GeoIP mapper.
+const DefaultGeoPrefix = "geo_"
+
+// DefaultRemoteAddrField is the nginx variable with the client address.
+const DefaultRemoteAddrField = "remote_addr"
+
+// GeoIP implements the Mapper interface to enrich entries with the country, city
+// and ASN of the client address looked up in local MMDB databases, e.g. GeoLite2
+// City and ASN ones. Data found in several databases is merged.
+//
+// The client address is taken from Field, DefaultRemoteAddrField if empty. If
+// Proxies are configured, the ForwardedField (X-Forwarded-For header) is honored
+// for requests coming from trusted proxies.
+//
+// Geo fields are set as a nested Entry in Target field, or as flat fields with
+// the Prefix if Target is empty. Entries with invalid or unknown address are
+// passed unchanged.
+type GeoIP struct {
+	Field          string
+	ForwardedField string
+	Proxies        *TrustedProxies
+	Target         string
+	Prefix         string
+	Language       string
+	DBs            []*GeoDB
+}
+
+// NewGeoIP creates a GeoIP mapper for the remote_addr field setting flat fields
+// with the DefaultGeoPrefix, e.g. geo_country_code and geo_asn.
+func NewGeoIP(dbs ...*GeoDB) *GeoIP {
+	return &GeoIP{
+		Field:          DefaultRemoteAddrField,
+		ForwardedField: DefaultForwardedField,
+		Prefix:         DefaultGeoPrefix,
+		Language:       "en",
+		DBs:            dbs,
+	}
+}
+
+// Map sets the geo fields of the client address.
+func (m *GeoIP) Map(entry *Entry) *Entry {
+	addr, ok := clientAddr(entry, m.Field, m.ForwardedField, m.Proxies)
+	if !ok {
+		return entry
+	}
+	info := &GeoInfo{}
+	var known bool
+	for _, db := range m.DBs {
+		found, err := db.Lookup(addr, m.Language, info)
+		if err != nil {
+			handleError(err)
+			continue
+		}
+		known = known || found
+	}
+	if !known {
+		return entry
+	}
+	if m.Target != "" {
+		nested := NewEmptyEntry()
+		info.SetFields(nested, "")
+		entry.SetEntryField(m.Target, nested)
+	} else {
+		info.SetFields(entry, m.Prefix)
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *GeoIP) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// clientAddr returns the client address of the entry resolved by proxies. Invalid
+// addresses are reported to ErrorHandler.
+func clientAddr(entry *Entry, field, forwardedField string, proxies *TrustedProxies) (netip.Addr, bool) {
+	if field == "" {
+		field = DefaultRemoteAddrField
+	}
+	remote, err := entry.StringField(field)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	var forwarded string
+	if proxies != nil && forwardedField != "" {
+		forwarded, _ = entry.StringField(forwardedField)
+	}
+	addr, err := proxies.ClientIP(remote, forwarded)
+	if err != nil {
+		handleError(err)
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
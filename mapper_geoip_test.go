@@ -0,0 +1,245 @@
+package gonx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// mmdbEncode encodes the value in MaxMind DB data section format.
+func mmdbEncode(buf *bytes.Buffer, value any) {
+	control := func(typ int, size int) {
+		var extra []byte
+		if size >= 29 {
+			extra = []byte{byte(size - 29)}
+			size = 29
+		}
+		if typ > 7 {
+			buf.WriteByte(byte(size))
+			buf.WriteByte(byte(typ - 7))
+		} else {
+			buf.WriteByte(byte(typ<<5 | size))
+		}
+		buf.Write(extra)
+	}
+	unsigned := func(typ int, v uint64) {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		n := 0
+		for n < 8 && b[n] == 0 {
+			n++
+		}
+		control(typ, 8-n)
+		buf.Write(b[n:])
+	}
+	switch v := value.(type) {
+	case string:
+		control(2, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(3, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		unsigned(5, uint64(v))
+	case uint32:
+		unsigned(6, uint64(v))
+	case uint64:
+		unsigned(9, v)
+	case []any:
+		control(11, len(v))
+		for _, item := range v {
+			mmdbEncode(buf, item)
+		}
+	case map[string]any:
+		control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			mmdbEncode(buf, key)
+			mmdbEncode(buf, v[key])
+		}
+	default:
+		panic("unsupported mmdb value")
+	}
+}
+
+// buildMMDB creates an IPv4 MaxMind DB with 24 bit records for given networks.
+func buildMMDB(networks map[string]map[string]any) []byte {
+	type node [2]int // -1 empty, >= 0 node index, < -1 data
+	nodes := []node{{-1, -1}}
+	var data bytes.Buffer
+	var offsets []int
+	for cidr, record := range networks {
+		prefix := netip.MustParsePrefix(cidr)
+		offsets = append(offsets, data.Len())
+		mmdbEncode(&data, record)
+		ip := prefix.Addr().As4()
+		current := 0
+		for bit := 0; bit < prefix.Bits(); bit++ {
+			side := int(ip[bit/8]>>(7-bit%8)) & 1
+			if bit == prefix.Bits()-1 {
+				nodes[current][side] = -2 - (len(offsets) - 1)
+				break
+			}
+			if nodes[current][side] < 0 {
+				nodes = append(nodes, node{-1, -1})
+				nodes[current][side] = len(nodes) - 1
+			}
+			current = nodes[current][side]
+		}
+	}
+
+	var db bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, record := range n {
+			value := count
+			if record >= 0 {
+				value = record
+			} else if record < -1 {
+				value = count + 16 + offsets[-2-record]
+			}
+			db.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	db.Write(make([]byte, 16))
+	db.Write(data.Bytes())
+	db.WriteString("\xab\xcd\xefMaxMind.com")
+	mmdbEncode(&db, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1700000000),
+		"database_type":               "Test",
+		"description":                 map[string]any{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+	})
+	return db.Bytes()
+}
+
+func TestGeoIP(t *testing.T) {
+	Convey("Test GeoIP enrichment", t, func() {
+		city, err := NewGeoDB(buildMMDB(map[string]map[string]any{
+			"81.2.69.0/24": {
+				"continent": map[string]any{"code": "EU"},
+				"country": map[string]any{
+					"iso_code": "GB",
+					"names":    map[string]any{"en": "United Kingdom", "de": "Vereinigtes Königreich"},
+				},
+				"subdivisions": []any{map[string]any{"names": map[string]any{"en": "England"}}},
+				"city":         map[string]any{"names": map[string]any{"en": "London"}},
+				"location":     map[string]any{"latitude": 51.5142, "longitude": -0.0931},
+			},
+		}))
+		So(err, ShouldBeNil)
+
+		filename := filepath.Join(t.TempDir(), "asn.mmdb")
+		So(os.WriteFile(filename, buildMMDB(map[string]map[string]any{
+			"81.2.0.0/16": {
+				"autonomous_system_number":       uint32(20712),
+				"autonomous_system_organization": "Andrews & Arnold Ltd",
+			},
+		}), 0644), ShouldBeNil)
+		asn, err := OpenGeoDB(filename)
+		So(err, ShouldBeNil)
+		defer asn.Close()
+
+		m := NewGeoIP(city, asn)
+
+		Convey("Merge databases data", func() {
+			entry := m.Map(NewEntry(Fields{"remote_addr": "81.2.69.160"}))
+			country, _ := entry.StringField("geo_country_code")
+			So(country, ShouldEqual, "GB")
+			name, _ := entry.StringField("geo_city")
+			So(name, ShouldEqual, "London")
+			region, _ := entry.StringField("geo_region")
+			So(region, ShouldEqual, "England")
+			lat, _ := entry.FloatField("geo_latitude")
+			So(lat, ShouldAlmostEqual, 51.5142)
+			number, _ := entry.StringField("geo_asn")
+			So(number, ShouldEqual, "20712")
+		})
+
+		Convey("Partially known address", func() {
+			entry := m.Map(NewEntry(Fields{"remote_addr": "81.2.1.1"}))
+			country, _ := entry.StringField("geo_country_code")
+			So(country, ShouldEqual, "")
+			org, _ := entry.StringField("geo_as_org")
+			So(org, ShouldEqual, "Andrews & Arnold Ltd")
+		})
+
+		Convey("Unknown and invalid addresses", func() {
+			entry := m.Map(NewEntry(Fields{"remote_addr": "10.0.0.1"}))
+			So(entry.Fields, ShouldHaveLength, 1)
+			entry = m.Map(NewEntry(Fields{"remote_addr": "-"}))
+			So(entry.Fields, ShouldHaveLength, 1)
+		})
+
+		Convey("Nested entry and language", func() {
+			m.Target = "geo"
+			m.Language = "de"
+			entry := m.Map(NewEntry(Fields{"remote_addr": "81.2.69.1"}))
+			geo, err := entry.EntryField("geo")
+			So(err, ShouldBeNil)
+			country, _ := geo.StringField("country")
+			So(country, ShouldEqual, "Vereinigtes Königreich")
+		})
+
+		Convey("Forwarded for trusted proxies", func() {
+			entry := NewEntry(Fields{
+				"remote_addr":          "10.0.0.1",
+				"http_x_forwarded_for": "81.2.69.10, 10.0.0.2",
+			})
+			m.Map(entry)
+			_, err := entry.StringField("geo_country_code")
+			So(err, ShouldNotBeNil)
+
+			m.Proxies, err = NewTrustedProxies("10.0.0.0/8")
+			So(err, ShouldBeNil)
+			m.Map(entry)
+			country, _ := entry.StringField("geo_country_code")
+			So(country, ShouldEqual, "GB")
+		})
+	})
+
+	Convey("Test trusted proxies", t, func() {
+		proxies, err := NewTrustedProxies("10.0.0.0/8", "192.168.1.1")
+		So(err, ShouldBeNil)
+
+		client := func(remote, forwarded string) string {
+			addr, err := proxies.ClientIP(remote, forwarded)
+			if err != nil {
+				return ""
+			}
+			return addr.String()
+		}
+		// Untrusted remote address can not be overridden
+		So(client("203.0.113.5", "1.1.1.1"), ShouldEqual, "203.0.113.5")
+		So(client("192.168.1.1", "1.1.1.1, 2.2.2.2"), ShouldEqual, "2.2.2.2")
+		So(client("10.1.1.1", "1.1.1.1, 2.2.2.2, 10.0.0.3"), ShouldEqual, "2.2.2.2")
+		So(client("10.1.1.1", "10.0.0.5"), ShouldEqual, "10.0.0.5")
+		So(client("10.1.1.1", "garbage, 2.2.2.2"), ShouldEqual, "2.2.2.2")
+		// Malformed hop is not skipped to take the trusted proxy for the client
+		So(client("10.1.1.1", "2.2.2.2, garbage, 10.0.0.2"), ShouldEqual, "")
+		_, err = proxies.ClientIP("10.1.1.1", "garbage, 10.0.0.2")
+		So(err, ShouldNotBeNil)
+		So(client("10.1.1.1", "-"), ShouldEqual, "10.1.1.1")
+		So(client("::ffff:10.1.1.1", ""), ShouldEqual, "10.1.1.1")
+		So(client("unknown", ""), ShouldEqual, "")
+
+		_, err = NewTrustedProxies("10.0.0.0/33")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,195 @@
+package gonx
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PathRule replaces path segments matching the Regexp with the Placeholder.
+// Segments shorter than MinLength are skipped.
+type PathRule struct {
+	Regexp      *regexp.Regexp
+	Placeholder string
+	MinLength   int
+}
+
+// DefaultPathRules detect UUIDs, numeric IDs, hashes (MD5, SHA-1, SHA-256) and
+// other hex tokens of at least 8 characters containing a digit.
+var DefaultPathRules = []PathRule{
+	{regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), ":uuid", 0},
+	{regexp.MustCompile(`^\d+$`), ":id", 0},
+	{regexp.MustCompile(`^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`), ":hash", 0},
+	{regexp.MustCompile(`^[0-9a-fA-F]*\d[0-9a-fA-F]*$`), ":hex", 8},
+}
+
+// PathNormalizer implements the Mapper interface to replace variable path
+// segments, e.g. `/users/123/orders?page=2` becomes `/users/:id/orders`, so
+// entries could be grouped per endpoint. Query string is removed.
+//
+// The first of Rules matching a segment is applied, DefaultPathRules are used if
+// Rules is nil. The result is stored in Target field, or replaces the original
+// value if Target is empty.
+type PathNormalizer struct {
+	Field  string
+	Target string
+	Rules  []PathRule
+}
+
+// NewPathNormalizer creates a PathNormalizer for the field with default rules.
+func NewPathNormalizer(field string) *PathNormalizer {
+	return &PathNormalizer{Field: field}
+}
+
+// Map normalizes the field value.
+func (m *PathNormalizer) Map(entry *Entry) *Entry {
+	val, err := entry.StringField(m.Field)
+	if err != nil {
+		return entry
+	}
+	entry.SetField(target(m.Target, m.Field), m.Normalize(val))
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *PathNormalizer) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Normalize returns the path with variable segments replaced by placeholders.
+func (m *PathNormalizer) Normalize(path string) string {
+	rules := m.Rules
+	if rules == nil {
+		rules = DefaultPathRules
+	}
+	segments := strings.Split(stripQuery(path), "/")
+	for i, segment := range segments {
+		for _, rule := range rules {
+			if len(segment) < rule.MinLength {
+				continue
+			}
+			if rule.Regexp.MatchString(segment) {
+				segments[i] = rule.Placeholder
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// DefaultRouteCardinality is the default number of distinct values of a path
+// segment above which RouteLearner treats it as a parameter.
+const DefaultRouteCardinality = 20
+
+// RouteParam is the placeholder of path segments learned as parameters.
+const RouteParam = ":param"
+
+// RouteLearner learns route templates from a sample of request paths. Paths are
+// normalized first, then segments with more than Cardinality distinct values at
+// the same position of the path tree are clustered into RouteParam.
+//
+//	learner := NewRouteLearner(0)
+//	for _, path := range sample {
+//		learner.Learn(path)
+//	}
+//	route := learner.Route("request_uri")
+type RouteLearner struct {
+	Cardinality int
+	Normalizer  *PathNormalizer
+	root        *routeNode
+}
+
+type routeNode struct {
+	children map[string]*routeNode
+	end      bool
+}
+
+func newRouteNode() *routeNode {
+	return &routeNode{children: make(map[string]*routeNode)}
+}
+
+// NewRouteLearner creates a learner with given cardinality threshold, zero means
+// DefaultRouteCardinality.
+func NewRouteLearner(cardinality int) *RouteLearner {
+	if cardinality <= 0 {
+		cardinality = DefaultRouteCardinality
+	}
+	return &RouteLearner{
+		Cardinality: cardinality,
+		Normalizer:  &PathNormalizer{},
+		root:        newRouteNode(),
+	}
+}
+
+// Learn adds the path to the sample.
+func (l *RouteLearner) Learn(path string) {
+	node := l.root
+	for _, segment := range splitPath(l.Normalizer.Normalize(path)) {
+		child, ok := node.children[segment]
+		if !ok {
+			child = newRouteNode()
+			node.children[segment] = child
+		}
+		node = child
+	}
+	node.end = true
+}
+
+// Templates returns learned route templates. Templates with less parameters go
+// first, so static routes take precedence when matched in order.
+func (l *RouteLearner) Templates() []string {
+	var templates []string
+	l.collect(l.cluster(l.root), nil, &templates)
+	sort.SliceStable(templates, func(i, j int) bool {
+		pi, pj := strings.Count(templates[i], "/:"), strings.Count(templates[j], "/:")
+		if pi != pj {
+			return pi < pj
+		}
+		return templates[i] < templates[j]
+	})
+	return templates
+}
+
+// Route returns a Route mapper for the field with learned templates.
+func (l *RouteLearner) Route(field string) *Route {
+	return NewRoute(field, l.Templates()...)
+}
+
+// cluster returns a copy of the tree with high cardinality children merged.
+func (l *RouteLearner) cluster(node *routeNode) *routeNode {
+	clustered := newRouteNode()
+	clustered.end = node.end
+	if len(node.children) > l.Cardinality {
+		merged := newRouteNode()
+		for _, child := range node.children {
+			mergeRouteNodes(merged, child)
+		}
+		clustered.children[RouteParam] = l.cluster(merged)
+		return clustered
+	}
+	for segment, child := range node.children {
+		clustered.children[segment] = l.cluster(child)
+	}
+	return clustered
+}
+
+func mergeRouteNodes(dst, src *routeNode) {
+	dst.end = dst.end || src.end
+	for segment, child := range src.children {
+		next, ok := dst.children[segment]
+		if !ok {
+			next = newRouteNode()
+			dst.children[segment] = next
+		}
+		mergeRouteNodes(next, child)
+	}
+}
+
+func (l *RouteLearner) collect(node *routeNode, prefix []string, templates *[]string) {
+	if node.end {
+		*templates = append(*templates, "/"+strings.Join(prefix, "/"))
+	}
+	for segment, child := range node.children {
+		l.collect(child, append(prefix[:len(prefix):len(prefix)], segment), templates)
+	}
+}
@@ -0,0 +1,202 @@
+package gonx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactMask replaces masked fields and scrubbed values.
+const DefaultRedactMask = "[REDACTED]"
+
+// RedactRule scrubs substrings of values matching the Regexp. Replacement may
+// refer to regexp groups, see regexp.Regexp.Expand, and to the mask as ${mask}.
+// If Validate is set, matches it rejects are kept, e.g. to check card numbers.
+type RedactRule struct {
+	Regexp      *regexp.Regexp
+	Replacement string
+	Validate    func(match string) bool
+}
+
+// Built-in redaction rules.
+var (
+	EmailRule = RedactRule{
+		Regexp:      regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Replacement: "${mask}",
+	}
+	CreditCardRule = RedactRule{
+		Regexp:      regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Replacement: "${mask}",
+		Validate:    luhnValid,
+	}
+	AuthTokenRule = RedactRule{
+		Regexp:      regexp.MustCompile(`(?i)\b(Bearer|Basic|Token)\s+[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "$1 ${mask}",
+	}
+	QueryTokenRule = RedactRule{
+		Regexp: regexp.MustCompile(`(?i)([?&;](?:token|access_token|refresh_token|id_token|api_key|apikey|key|` +
+			`password|passwd|pwd|secret|auth|sig|signature|session|sessionid|sid)=)[^&;#\s"]*`),
+		Replacement: "${1}${mask}",
+	}
+	IPRule = RedactRule{
+		Regexp:      regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]|\b\d{1,3}(?:\.\d{1,3}){3}\b`),
+		Replacement: "${mask}",
+		Validate:    ipValid,
+	}
+)
+
+// DefaultRedactRules scrub emails, credit card numbers, authorization tokens,
+// secrets in query strings and IPv4 and IPv6 addresses.
+var DefaultRedactRules = []RedactRule{AuthTokenRule, QueryTokenRule, EmailRule, CreditCardRule, IPRule}
+
+// Redact implements the Mapper interface to remove personal data from entries
+// before sharing logs.
+//
+// Mask fields are replaced by the MaskValue, DefaultRedactMask if empty, e.g. for
+// authorization headers. Pseudonymize fields are replaced by a stable keyed
+// token, see Pseudonym, so e.g. the same IP address maps to the same token
+// across files while it can not be recovered without the Key. Without a Key
+// they are masked instead, as an unkeyed hash of an IP address or email is
+// easily reversed by hashing all candidates.
+//
+// Values of Scrub fields, or all other string fields if Scrub is empty, are
+// scrubbed by Rules, which default to DefaultRedactRules, so IP addresses are
+// masked unless their fields are pseudonymized. Use an empty non-nil Rules to
+// disable scrubbing.
+type Redact struct {
+	Mask         []string
+	Pseudonymize []string
+	Scrub        []string
+	Rules        []RedactRule
+	Key          []byte
+	MaskValue    string
+}
+
+// Map redacts the entry fields.
+func (m *Redact) Map(entry *Entry) *Entry {
+	mask := m.MaskValue
+	if mask == "" {
+		mask = DefaultRedactMask
+	}
+	done := make(map[string]bool, len(m.Mask)+len(m.Pseudonymize))
+	for _, name := range m.Mask {
+		if _, ok := entry.Fields[name]; ok {
+			entry.SetField(name, mask)
+		}
+		done[name] = true
+	}
+	for _, name := range m.Pseudonymize {
+		if val, err := entry.StringField(name); err == nil && val != "" && val != "-" {
+			if len(m.Key) == 0 {
+				entry.SetField(name, mask)
+			} else {
+				entry.SetField(name, Pseudonym(m.Key, val))
+			}
+		}
+		done[name] = true
+	}
+
+	rules := m.Rules
+	if rules == nil {
+		rules = DefaultRedactRules
+	}
+	if len(rules) == 0 {
+		return entry
+	}
+	scrub := func(name string) {
+		if val, ok := entry.Fields[name].(string); ok {
+			entry.SetField(name, ScrubString(val, mask, rules...))
+		}
+	}
+	if len(m.Scrub) > 0 {
+		for _, name := range m.Scrub {
+			scrub(name)
+		}
+		return entry
+	}
+	for name := range entry.Fields {
+		if !done[name] {
+			scrub(name)
+		}
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Redact) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// ScrubString replaces substrings of the value matched by the rules.
+func ScrubString(value, mask string, rules ...RedactRule) string {
+	for _, rule := range rules {
+		value = rule.apply(value, mask)
+	}
+	return value
+}
+
+func (r RedactRule) apply(value, mask string) string {
+	matches := r.Regexp.FindAllStringSubmatchIndex(value, -1)
+	if matches == nil {
+		return value
+	}
+	template := expandMask(r.Replacement, mask)
+	var result []byte
+	last := 0
+	for _, match := range matches {
+		if r.Validate != nil && !r.Validate(value[match[0]:match[1]]) {
+			continue
+		}
+		result = append(result, value[last:match[0]]...)
+		result = r.Regexp.ExpandString(result, template, value, match)
+		last = match[1]
+	}
+	return string(append(result, value[last:]...))
+}
+
+// expandMask substitutes the mask into the replacement template escaping `$`,
+// so it is not taken for a group reference.
+func expandMask(replacement, mask string) string {
+	return strings.ReplaceAll(replacement, "${mask}", strings.ReplaceAll(mask, "$", "$$"))
+}
+
+// pseudonymSize is the number of HMAC bytes used for the pseudonym.
+const pseudonymSize = 8
+
+// Pseudonym returns a stable token for the value keyed by the secret key. It is
+// the hex encoded truncated HMAC-SHA256, so it can not be reversed or guessed by
+// hashing candidate values without the key.
+func Pseudonym(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:pseudonymSize])
+}
+
+// ipValid reports whether the match is an IP address, not e.g. a time of day.
+func ipValid(match string) bool {
+	_, err := netip.ParseAddr(match)
+	return err == nil
+}
+
+// luhnValid reports whether the digits of the number pass the Luhn checksum.
+func luhnValid(number string) bool {
+	var sum, n int
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,85 @@
+package gonx
+
+import (
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRedact(t *testing.T) {
+	Convey("Test PII redaction", t, func() {
+		Convey("Scrub values", func() {
+			So(ScrubString("mail john.doe@example.com now", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "mail [REDACTED] now")
+			So(ScrubString("GET /login?user=bob&token=abc123&x=1 HTTP/1.1", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "GET /login?user=bob&token=[REDACTED]&x=1 HTTP/1.1")
+			So(ScrubString("/cb?access_token=eyJ.x-y", "***", QueryTokenRule),
+				ShouldEqual, "/cb?access_token=***")
+			So(ScrubString("Authorization: Bearer eyJhbGciOi.J9x_y==", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "Authorization: Bearer [REDACTED]")
+			So(ScrubString("card 4111 1111 1111 1111 paid", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "card [REDACTED] paid")
+			// Numbers failing the Luhn checksum are kept
+			So(ScrubString("order 1234567890123456", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "order 1234567890123456")
+			So(ScrubString("from 203.0.113.7:443 via 2001:db8::1 and ::ffff:10.0.0.1", DefaultRedactMask, IPRule),
+				ShouldEqual, "from [REDACTED]:443 via [REDACTED] and [REDACTED]")
+			// Times and versions are not taken for addresses
+			So(ScrubString("[08/Nov/2013:13:39:18 +0000] nginx/1.25.3 999.1.1.1", DefaultRedactMask, DefaultRedactRules...),
+				ShouldEqual, "[08/Nov/2013:13:39:18 +0000] nginx/1.25.3 999.1.1.1")
+			// Mask is not taken for a group reference
+			So(ScrubString("a@b.io", "$1", EmailRule), ShouldEqual, "$1")
+		})
+
+		Convey("Mask, pseudonymize and scrub entry", func() {
+			m := &Redact{
+				Mask:         []string{"http_authorization"},
+				Pseudonymize: []string{"remote_addr"},
+				Key:          []byte("secret"),
+			}
+			entry := m.Map(NewEntry(Fields{
+				"remote_addr":        "203.0.113.7",
+				"http_authorization": "Basic dXNlcjpwYXNz",
+				"request":            "GET /reset?email=jane@example.org HTTP/1.1",
+				"status":             "200",
+				"http_x_real_ip":     "198.51.100.1",
+			}))
+
+			auth, _ := entry.StringField("http_authorization")
+			So(auth, ShouldEqual, DefaultRedactMask)
+			request, _ := entry.StringField("request")
+			So(request, ShouldEqual, "GET /reset?email=[REDACTED] HTTP/1.1")
+			status, _ := entry.StringField("status")
+			So(status, ShouldEqual, "200")
+			realIP, _ := entry.StringField("http_x_real_ip")
+			So(realIP, ShouldEqual, DefaultRedactMask)
+
+			addr, _ := entry.StringField("remote_addr")
+			So(addr, ShouldEqual, Pseudonym([]byte("secret"), "203.0.113.7"))
+			So(addr, ShouldHaveLength, 16)
+			So(addr, ShouldNotEqual, Pseudonym([]byte("other"), "203.0.113.7"))
+			So(addr, ShouldNotEqual, Pseudonym([]byte("secret"), "203.0.113.8"))
+		})
+
+		Convey("Mask instead of pseudonymizing without a key", func() {
+			m := &Redact{Pseudonymize: []string{"remote_addr"}}
+			entry := m.Map(NewEntry(Fields{"remote_addr": "203.0.113.7"}))
+			addr, _ := entry.StringField("remote_addr")
+			So(addr, ShouldEqual, DefaultRedactMask)
+			So(addr, ShouldNotEqual, Pseudonym(nil, "203.0.113.7"))
+		})
+
+		Convey("Scrub given fields only", func() {
+			m := &Redact{
+				Scrub: []string{"request"},
+				Rules: []RedactRule{{Regexp: regexp.MustCompile(`\d{3}-\d{4}`), Replacement: "${mask}"}},
+			}
+			entry := m.Map(NewEntry(Fields{"request": "call 555-1234", "referer": "555-1234"}))
+			request, _ := entry.StringField("request")
+			So(request, ShouldEqual, "call [REDACTED]")
+			referer, _ := entry.StringField("referer")
+			So(referer, ShouldEqual, "555-1234")
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package gonx
+
+import (
+	"strings"
+)
+
+// Route implements the Mapper interface to replace request paths with route
+// templates, e.g. `/users/123/orders` with `/users/:id/orders`.
+//
+// A template segment starting with `:` matches any single path segment and `*`
+// matches the rest of the path. The first matching template is stored in Target
+// field, or replaces the original value if Target is empty. Query string is
+// ignored. Paths which do not match any template are left untouched.
+type Route struct {
+	Field     string
+	Target    string
+	templates [][]string
+	sources   []string
+}
+
+// NewRoute creates a Route mapper for the field with given templates.
+func NewRoute(field string, templates ...string) *Route {
+	m := &Route{Field: field}
+	for _, template := range templates {
+		m.templates = append(m.templates, splitPath(template))
+		m.sources = append(m.sources, template)
+	}
+	return m
+}
+
+// Map replaces the field value with a matching route template.
+func (m *Route) Map(entry *Entry) *Entry {
+	val, err := entry.StringField(m.Field)
+	if err != nil {
+		return entry
+	}
+	if template, ok := m.Match(val); ok {
+		entry.SetField(target(m.Target, m.Field), template)
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *Route) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
+
+// Match returns the first template matching the path.
+func (m *Route) Match(path string) (string, bool) {
+	segments := splitPath(stripQuery(path))
+	for i, template := range m.templates {
+		if matchSegments(template, segments) {
+			return m.sources[i], true
+		}
+	}
+	return "", false
+}
+
+func matchSegments(template, segments []string) bool {
+	for i, t := range template {
+		if t == "*" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if !strings.HasPrefix(t, ":") && t != segments[i] {
+			return false
+		}
+	}
+	return len(template) == len(segments)
+}
+
+// stripQuery removes query string from the request URI.
+func stripQuery(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		return uri[:i]
+	}
+	return uri
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
@@ -0,0 +1,209 @@
+package gonx
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMapper(t *testing.T) {
+	Convey("Test built-in mappers", t, func() {
+		entry := NewEntry(Fields{
+			"host":            "Example.COM",
+			"request_uri":     "/users/123/orders?page=2",
+			"body_bytes_sent": "2048",
+		})
+
+		Convey("Replace", func() {
+			m := &Replace{Field: "request_uri", Regexp: regexp.MustCompile(`\?.*$`)}
+			So(m.Map(entry), ShouldEqual, entry)
+			val, _ := entry.StringField("request_uri")
+			So(val, ShouldEqual, "/users/123/orders")
+
+			m = &Replace{Field: "request_uri", Regexp: regexp.MustCompile(`/\d+`), Replacement: "/:id", Target: "path"}
+			m.Map(entry)
+			val, _ = entry.StringField("path")
+			So(val, ShouldEqual, "/users/:id/orders")
+		})
+
+		Convey("Lowercase", func() {
+			(&Lowercase{Fields: []string{"host", "missing"}}).Map(entry)
+			val, _ := entry.StringField("host")
+			So(val, ShouldEqual, "example.com")
+		})
+
+		Convey("Rename, copy and drop", func() {
+			(&Rename{Fields: map[string]string{"host": "server"}}).Map(entry)
+			_, err := entry.StringField("host")
+			So(err, ShouldNotBeNil)
+			val, _ := entry.StringField("server")
+			So(val, ShouldEqual, "Example.COM")
+
+			(&Copy{Fields: map[string]string{"server": "vhost"}}).Map(entry)
+			val, _ = entry.StringField("vhost")
+			So(val, ShouldEqual, "Example.COM")
+
+			(&Drop{Fields: []string{"server", "vhost"}}).Map(entry)
+			So(entry.Fields, ShouldHaveLength, 2)
+		})
+
+		Convey("Arithmetic", func() {
+			(&Arithmetic{Target: "bytes_kb", Field: "body_bytes_sent", Op: "/", Operand: 1024}).Map(entry)
+			val, _ := entry.FloatField("bytes_kb")
+			So(val, ShouldEqual, 2)
+
+			// Non-numeric values are left untouched
+			(&Arithmetic{Field: "host", Op: "*", Operand: 2}).Map(entry)
+			host, _ := entry.StringField("host")
+			So(host, ShouldEqual, "Example.COM")
+		})
+
+		Convey("Route", func() {
+			m := NewRoute("request_uri", "/users/:id", "/users/:id/orders", "/static/*")
+			So(m.Map(entry), ShouldEqual, entry)
+			val, _ := entry.StringField("request_uri")
+			So(val, ShouldEqual, "/users/:id/orders")
+
+			route, ok := m.Match("/static/css/main.css")
+			So(ok, ShouldBeTrue)
+			So(route, ShouldEqual, "/static/*")
+
+			_, ok = m.Match("/users")
+			So(ok, ShouldBeFalse)
+		})
+	})
+
+	Convey("Test mappers in pipelines", t, func() {
+		Convey("Chain applies mappers and filters in order", func() {
+			input := make(chan *Entry, 3)
+			output := make(chan *Entry, 1)
+			input <- NewEntry(Fields{"host": "A.com"})
+			input <- NewEntry(Fields{"host": "a.COM"})
+			input <- NewEntry(Fields{"host": "b.com"})
+			close(input)
+
+			chain := NewChain(
+				&Lowercase{Fields: []string{"host"}},
+				&Equal{Field: "host", Value: "a.com"},
+				&Count{},
+			)
+			chain.Reduce(input, output)
+
+			result := <-output
+			count, _ := result.FloatField("count")
+			So(count, ShouldEqual, 2)
+		})
+
+		Convey("Chain maps a copy of the entry read by other reducers", func() {
+			hosts := []string{"A.com", "a.COM", "b.com"}
+			input := make(chan *Entry, 300)
+			output := make(chan *Entry, 1)
+			for i := 0; i < 300; i++ {
+				input <- NewEntry(Fields{"host": hosts[i%len(hosts)]})
+			}
+			close(input)
+
+			chain := NewChain(
+				NewChain(&Lowercase{Fields: []string{"host"}}, &Equal{Field: "host", Value: "a.com"}, &Count{}),
+				NewTogether("hosts", NewGroupBy([]string{"host"}, &Count{})),
+			)
+			chain.Reduce(input, output)
+
+			result := <-output
+			count, _ := result.FloatField("count")
+			So(count, ShouldEqual, 200)
+			groups, err := result.EntryList("hosts")
+			So(err, ShouldBeNil)
+			So(groups, ShouldHaveLength, 3)
+			for _, group := range groups {
+				count, _ := group.FloatField("count")
+				So(count, ShouldEqual, 100)
+			}
+		})
+
+		Convey("MapperParser drops entries without errors", func() {
+			var errs []error
+			ErrorHandler = func(err error) { errs = append(errs, err) }
+			defer func() { ErrorHandler = nil }()
+
+			parser := NewMapperParser(NewParser("$status $bytes"),
+				MapperFunc(func(entry *Entry) *Entry {
+					if status, _ := entry.StringField("status"); status != "200" {
+						return nil
+					}
+					return entry
+				}),
+				&Arithmetic{Target: "kb", Field: "bytes", Op: "/", Operand: 1024},
+			)
+			file := strings.NewReader("200 1024\n404 512\n200 3072\n")
+			output := MapReduce(file, parser, &Sum{map[string]string{"kb": "kb"}})
+
+			result := <-output
+			sum, _ := result.FloatField("kb")
+			So(sum, ShouldEqual, 4)
+			So(errs, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestPathNormalizer(t *testing.T) {
+	Convey("Test path normalization", t, func() {
+		m := NewPathNormalizer("request_uri")
+		So(m.Normalize("/users/123/orders?page=2"), ShouldEqual, "/users/:id/orders")
+		So(m.Normalize("/items/3f2504e0-4f89-11d3-9a0c-0305e82c3301"), ShouldEqual, "/items/:uuid")
+		So(m.Normalize("/files/d41d8cd98f00b204e9800998ecf8427e/raw"), ShouldEqual, "/files/:hash/raw")
+		So(m.Normalize("/s/5f1a9c2be"), ShouldEqual, "/s/:hex")
+		// Short hex-like and plain words are kept
+		So(m.Normalize("/api/v2/cafe/beef12"), ShouldEqual, "/api/v2/cafe/beef12")
+
+		entry := NewEntry(Fields{"request_uri": "/users/42"})
+		m.Target = "route"
+		m.Map(entry)
+		route, _ := entry.StringField("route")
+		So(route, ShouldEqual, "/users/:id")
+	})
+
+	Convey("Test route learning", t, func() {
+		learner := NewRouteLearner(3)
+		for _, name := range []string{"alice", "bob", "carol", "dave", "eve"} {
+			learner.Learn("/profile/" + name)
+			learner.Learn("/profile/" + name + "/avatar")
+		}
+		learner.Learn("/orders/17")
+		learner.Learn("/orders/18?full=1")
+		learner.Learn("/about")
+
+		So(learner.Templates(), ShouldResemble, []string{
+			"/about",
+			"/orders/:id",
+			"/profile/:param",
+			"/profile/:param/avatar",
+		})
+
+		route := learner.Route("request_uri")
+		template, ok := route.Match("/profile/zed/avatar")
+		So(ok, ShouldBeTrue)
+		So(template, ShouldEqual, "/profile/:param/avatar")
+		template, _ = route.Match("/orders/:id")
+		So(template, ShouldEqual, "/orders/:id")
+
+		Convey("Group by learned route", func() {
+			input := make(chan *Entry, 3)
+			output := make(chan *Entry, 2)
+			for _, uri := range []string{"/profile/xavier", "/profile/yuri", "/about"} {
+				input <- route.Map(NewEntry(Fields{"request_uri": uri}))
+			}
+			close(input)
+
+			NewGroupBy([]string{"request_uri"}, &Count{}).Reduce(input, output)
+			counts := make(map[string]float64)
+			for result := range output {
+				uri, _ := result.StringField("request_uri")
+				counts[uri], _ = result.FloatField("count")
+			}
+			So(counts, ShouldResemble, map[string]float64{"/profile/:param": 2, "/about": 1})
+		})
+	})
+}
@@ -0,0 +1,357 @@
+package gonx
+
+import (
+	"container/list"
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed useragent_regexes.yaml
+var defaultUserAgentRegexes []byte
+
+// DefaultUserAgentCacheSize is the number of parsed user agents kept in cache.
+const DefaultUserAgentCacheSize = 4096
+
+// Device classes of UserAgentInfo.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// UserAgentInfo is a parsed user agent. Unknown families are "Other".
+type UserAgentInfo struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         string
+	DeviceClass    string
+	Bot            bool
+}
+
+// SetFields sets the user agent fields to the entry with given name prefix.
+func (info *UserAgentInfo) SetFields(entry *Entry, prefix string) {
+	entry.SetField(prefix+"browser", info.Browser)
+	entry.SetField(prefix+"browser_version", info.BrowserVersion)
+	entry.SetField(prefix+"os", info.OS)
+	entry.SetField(prefix+"os_version", info.OSVersion)
+	entry.SetField(prefix+"device", info.Device)
+	entry.SetField(prefix+"device_class", info.DeviceClass)
+	entry.SetField(prefix+"bot", strconv.FormatBool(info.Bot))
+}
+
+// Entry returns the user agent fields as a new Entry.
+func (info *UserAgentInfo) Entry() *Entry {
+	entry := NewEmptyEntry()
+	info.SetFields(entry, "")
+	return entry
+}
+
+// userAgentRegexes is the subset of uap-core regexes.yaml format supported.
+type userAgentRegexes struct {
+	UserAgentParsers []struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		FamilyReplacement string `yaml:"family_replacement"`
+		V1Replacement     string `yaml:"v1_replacement"`
+		V2Replacement     string `yaml:"v2_replacement"`
+		V3Replacement     string `yaml:"v3_replacement"`
+	} `yaml:"user_agent_parsers"`
+	OSParsers []struct {
+		Regex           string `yaml:"regex"`
+		RegexFlag       string `yaml:"regex_flag"`
+		OSReplacement   string `yaml:"os_replacement"`
+		OSV1Replacement string `yaml:"os_v1_replacement"`
+		OSV2Replacement string `yaml:"os_v2_replacement"`
+		OSV3Replacement string `yaml:"os_v3_replacement"`
+	} `yaml:"os_parsers"`
+	DeviceParsers []struct {
+		Regex             string `yaml:"regex"`
+		RegexFlag         string `yaml:"regex_flag"`
+		DeviceReplacement string `yaml:"device_replacement"`
+	} `yaml:"device_parsers"`
+}
+
+// userAgentRule extracts a family and up to three version parts. Replacements
+// may refer to regexp groups as $1, empty ones default to the consecutive groups.
+type userAgentRule struct {
+	re      *regexp.Regexp
+	family  string
+	version [3]string
+}
+
+func newUserAgentRule(regex, flag, family string, version ...string) (*userAgentRule, error) {
+	if flag == "i" {
+		regex = "(?i)" + regex
+	}
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, err
+	}
+	rule := &userAgentRule{re: re, family: family}
+	copy(rule.version[:], version)
+	return rule, nil
+}
+
+// match returns the family and the version or false if the rule does not match.
+func (r *userAgentRule) match(userAgent string) (string, string, bool) {
+	groups := r.re.FindStringSubmatch(userAgent)
+	if groups == nil {
+		return "", "", false
+	}
+	group := func(i int) string {
+		if i < len(groups) {
+			return groups[i]
+		}
+		return ""
+	}
+	family := group(1)
+	if r.family != "" {
+		family = expandGroups(r.family, groups)
+	}
+	var parts []string
+	for i, replacement := range r.version {
+		part := group(i + 2)
+		if replacement != "" {
+			part = expandGroups(replacement, groups)
+		}
+		if part == "" {
+			break
+		}
+		parts = append(parts, part)
+	}
+	return strings.TrimSpace(family), strings.Join(parts, "."), true
+}
+
+var groupRefRe = regexp.MustCompile(`\$(\d)`)
+
+func expandGroups(template string, groups []string) string {
+	return groupRefRe.ReplaceAllStringFunc(template, func(ref string) string {
+		i := int(ref[1] - '0')
+		if i < len(groups) {
+			return groups[i]
+		}
+		return ""
+	})
+}
+
+// UserAgentParser parses user agents using rules in the uap-core regexes.yaml
+// format. Results are cached, the parser is safe for concurrent use.
+type UserAgentParser struct {
+	browsers []*userAgentRule
+	os       []*userAgentRule
+	devices  []*userAgentRule
+	cache    *userAgentCache
+}
+
+// NewUserAgentParser creates a parser from the regexes.yaml data keeping up to
+// cacheSize user agents in cache, zero disables caching.
+func NewUserAgentParser(regexes []byte, cacheSize int) (*UserAgentParser, error) {
+	var data userAgentRegexes
+	if err := yaml.Unmarshal(regexes, &data); err != nil {
+		return nil, err
+	}
+	p := &UserAgentParser{cache: newUserAgentCache(cacheSize)}
+	for _, r := range data.UserAgentParsers {
+		rule, err := newUserAgentRule(r.Regex, r.RegexFlag, r.FamilyReplacement, r.V1Replacement, r.V2Replacement, r.V3Replacement)
+		if err != nil {
+			return nil, fmt.Errorf("user agent rule '%v': %v", r.Regex, err)
+		}
+		p.browsers = append(p.browsers, rule)
+	}
+	for _, r := range data.OSParsers {
+		rule, err := newUserAgentRule(r.Regex, r.RegexFlag, r.OSReplacement, r.OSV1Replacement, r.OSV2Replacement, r.OSV3Replacement)
+		if err != nil {
+			return nil, fmt.Errorf("os rule '%v': %v", r.Regex, err)
+		}
+		p.os = append(p.os, rule)
+	}
+	for _, r := range data.DeviceParsers {
+		rule, err := newUserAgentRule(r.Regex, r.RegexFlag, r.DeviceReplacement)
+		if err != nil {
+			return nil, fmt.Errorf("device rule '%v': %v", r.Regex, err)
+		}
+		p.devices = append(p.devices, rule)
+	}
+	return p, nil
+}
+
+// LoadUserAgentParser creates a parser from a local regexes.yaml file, e.g. the
+// full rule set of uap-core.
+func LoadUserAgentParser(filename string, cacheSize int) (*UserAgentParser, error) {
+	regexes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewUserAgentParser(regexes, cacheSize)
+}
+
+var (
+	defaultUserAgentParser     *UserAgentParser
+	defaultUserAgentParserOnce sync.Once
+)
+
+// DefaultUserAgentParser returns a shared parser with the embedded compact rule
+// set covering common browsers, operating systems, devices and bots.
+func DefaultUserAgentParser() *UserAgentParser {
+	defaultUserAgentParserOnce.Do(func() {
+		var err error
+		defaultUserAgentParser, err = NewUserAgentParser(defaultUserAgentRegexes, DefaultUserAgentCacheSize)
+		if err != nil {
+			panic(err)
+		}
+	})
+	return defaultUserAgentParser
+}
+
+// Parse returns the parsed user agent. The result is shared via cache and must
+// not be modified.
+func (p *UserAgentParser) Parse(userAgent string) *UserAgentInfo {
+	if info, ok := p.cache.get(userAgent); ok {
+		return info
+	}
+	info := &UserAgentInfo{Bot: IsBot(userAgent)}
+	info.Browser, info.BrowserVersion = matchUserAgent(p.browsers, userAgent)
+	info.OS, info.OSVersion = matchUserAgent(p.os, userAgent)
+	info.Device, _ = matchUserAgent(p.devices, userAgent)
+	info.DeviceClass = deviceClass(info, userAgent)
+	p.cache.add(userAgent, info)
+	return info
+}
+
+func matchUserAgent(rules []*userAgentRule, userAgent string) (string, string) {
+	for _, rule := range rules {
+		if family, version, ok := rule.match(userAgent); ok {
+			return family, version
+		}
+	}
+	return "Other", ""
+}
+
+var (
+	tabletRe = regexp.MustCompile(`(?i)ipad|tablet|kindle|silk|playbook`)
+	mobileRe = regexp.MustCompile(`(?i)mobi|iphone|ipod|android|phone`)
+)
+
+func deviceClass(info *UserAgentInfo, userAgent string) string {
+	switch {
+	case info.Bot || info.Device == "Spider":
+		return DeviceBot
+	case tabletRe.MatchString(userAgent),
+		strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile"):
+		return DeviceTablet
+	case mobileRe.MatchString(userAgent):
+		return DeviceMobile
+	}
+	return DeviceDesktop
+}
+
+// userAgentCache is a concurrency safe LRU cache of parsed user agents.
+type userAgentCache struct {
+	size  int
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+type userAgentCacheItem struct {
+	key  string
+	info *UserAgentInfo
+}
+
+func newUserAgentCache(size int) *userAgentCache {
+	return &userAgentCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *userAgentCache) get(key string) (*UserAgentInfo, bool) {
+	if c.size <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*userAgentCacheItem).info, true
+}
+
+func (c *userAgentCache) add(key string, info *UserAgentInfo) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.order.PushFront(&userAgentCacheItem{key: key, info: info})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*userAgentCacheItem).key)
+	}
+}
+
+// DefaultUserAgentPrefix is the prefix of flat fields set by UserAgent mapper.
+const DefaultUserAgentPrefix = "ua_"
+
+// UserAgent implements the Mapper interface to enrich entries with browser, OS,
+// device and bot flag parsed from the user agent Field, DefaultUserAgentField if
+// empty. Parsed fields are set as a nested Entry in Target field, or as flat
+// fields with the Prefix if Target is empty. DefaultUserAgentParser is used if
+// Parser is nil.
+type UserAgent struct {
+	Field  string
+	Target string
+	Prefix string
+	Parser *UserAgentParser
+}
+
+// NewUserAgent creates a UserAgent mapper setting flat fields with the
+// DefaultUserAgentPrefix, e.g. ua_browser and ua_os.
+func NewUserAgent(field string) *UserAgent {
+	return &UserAgent{Field: field, Prefix: DefaultUserAgentPrefix}
+}
+
+// Map sets the parsed user agent fields.
+func (m *UserAgent) Map(entry *Entry) *Entry {
+	field := m.Field
+	if field == "" {
+		field = DefaultUserAgentField
+	}
+	val, err := entry.StringField(field)
+	if err != nil {
+		return entry
+	}
+	parser := m.Parser
+	if parser == nil {
+		parser = DefaultUserAgentParser()
+	}
+	info := parser.Parse(val)
+	if m.Target != "" {
+		entry.SetEntryField(m.Target, info.Entry())
+	} else {
+		info.SetFields(entry, m.Prefix)
+	}
+	return entry
+}
+
+// Reduce implements the Reducer interface. Go through input and apply Map.
+func (m *UserAgent) Reduce(input chan *Entry, output chan *Entry) {
+	reduceMapper(m, input, output)
+}
@@ -0,0 +1,108 @@
+package gonx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUserAgent(t *testing.T) {
+	Convey("Test user agent parsing", t, func() {
+		parser := DefaultUserAgentParser()
+
+		Convey("Desktop browser", func() {
+			info := parser.Parse("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36")
+			So(info.Browser, ShouldEqual, "Chrome")
+			So(info.BrowserVersion, ShouldEqual, "120.0.6099")
+			So(info.OS, ShouldEqual, "Windows")
+			So(info.OSVersion, ShouldEqual, "10")
+			So(info.DeviceClass, ShouldEqual, DeviceDesktop)
+			So(info.Bot, ShouldBeFalse)
+		})
+
+		Convey("Mobile browser", func() {
+			info := parser.Parse("Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1")
+			So(info.Browser, ShouldEqual, "Mobile Safari")
+			So(info.BrowserVersion, ShouldEqual, "17.1")
+			So(info.OS, ShouldEqual, "iOS")
+			So(info.OSVersion, ShouldEqual, "17.1.2")
+			So(info.Device, ShouldEqual, "iPhone")
+			So(info.DeviceClass, ShouldEqual, DeviceMobile)
+		})
+
+		Convey("Tablet", func() {
+			info := parser.Parse("Mozilla/5.0 (Linux; Android 13; SM-X706B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36")
+			So(info.OS, ShouldEqual, "Android")
+			So(info.OSVersion, ShouldEqual, "13")
+			So(info.Device, ShouldEqual, "Samsung SM-X706B")
+			So(info.DeviceClass, ShouldEqual, DeviceTablet)
+		})
+
+		Convey("Bot", func() {
+			info := parser.Parse("Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
+			So(info.Browser, ShouldEqual, "Googlebot")
+			So(info.Device, ShouldEqual, "Spider")
+			So(info.DeviceClass, ShouldEqual, DeviceBot)
+			So(info.Bot, ShouldBeTrue)
+		})
+
+		Convey("Unknown", func() {
+			info := parser.Parse("something")
+			So(info.Browser, ShouldEqual, "Other")
+			So(info.OS, ShouldEqual, "Other")
+		})
+
+		Convey("Cache", func() {
+			ua := "curl/8.4.0"
+			So(parser.Parse(ua), ShouldEqual, parser.Parse(ua))
+
+			cache := newUserAgentCache(2)
+			cache.add("a", &UserAgentInfo{})
+			cache.add("b", &UserAgentInfo{})
+			cache.get("a")
+			cache.add("c", &UserAgentInfo{})
+			_, ok := cache.get("b")
+			So(ok, ShouldBeFalse)
+			_, ok = cache.get("a")
+			So(ok, ShouldBeTrue)
+		})
+	})
+
+	Convey("Test custom regexes file", t, func() {
+		filename := filepath.Join(t.TempDir(), "regexes.yaml")
+		regexes := "user_agent_parsers:\n" +
+			"  - regex: '(MyApp)/(\\d+)\\.(\\d+)'\n" +
+			"    family_replacement: 'My $1'\n"
+		So(os.WriteFile(filename, []byte(regexes), 0644), ShouldBeNil)
+
+		parser, err := LoadUserAgentParser(filename, 0)
+		So(err, ShouldBeNil)
+		info := parser.Parse("MyApp/2.5 (Android)")
+		So(info.Browser, ShouldEqual, "My MyApp")
+		So(info.BrowserVersion, ShouldEqual, "2.5")
+		So(info.OS, ShouldEqual, "Other")
+
+		_, err = NewUserAgentParser([]byte("os_parsers:\n  - regex: '('\n"), 0)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test UserAgent mapper", t, func() {
+		ua := "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+
+		entry := NewEntry(Fields{"http_user_agent": ua})
+		NewUserAgent("").Map(entry)
+		browser, _ := entry.StringField("ua_browser")
+		So(browser, ShouldEqual, "Firefox")
+		bot, _ := entry.StringField("ua_bot")
+		So(bot, ShouldEqual, "false")
+
+		entry = NewEntry(Fields{"agent": ua})
+		(&UserAgent{Field: "agent", Target: "ua"}).Map(entry)
+		nested, err := entry.EntryField("ua")
+		So(err, ShouldBeNil)
+		os, _ := nested.StringField("os")
+		So(os, ShouldEqual, "Linux")
+	})
+}
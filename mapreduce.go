@@ -1,14 +1,19 @@
 package gonx
 
 import (
-	"bufio"
-	"bytes"
 	"io"
 	"sync"
 )
 
+// ErrorHandler is called for errors which do not stop the pipeline, e.g. lines
+// not matching the log format or entries with bad timestamps. Errors are
+// ignored if it is nil. It could be called from different goroutines.
+var ErrorHandler func(err error)
+
 func handleError(err error) {
-	//fmt.Fprintln(os.Stderr, err)
+	if ErrorHandler != nil {
+		ErrorHandler(err)
+	}
 }
 
 // MapReduce iterates over given file and map each it's line into Entry record using
@@ -17,9 +22,16 @@ func handleError(err error) {
 // works and fills input Entries channel until all lines will be read from
 // the fiven file.
 func MapReduce(file io.Reader, parser StringParser, reducer Reducer) chan *Entry {
+	return MapReduceInput(NewReaderInput(file, ""), parser, reducer)
+}
+
+// MapReduceInput works as MapReduce, but reads lines from the given Input. If the
+// Input implements Tagger, each Entry is tagged with its Line position.
+func MapReduceInput(input Input, parser StringParser, reducer Reducer) chan *Entry {
 	// Input file lines. This channel is unbuffered to publish
 	// next line to handle only when previous is taken by mapper.
-	var lines = make(chan string)
+	var lines = make(chan Line)
+	tagger, _ := input.(Tagger)
 
 	// Host thread to spawn new mappers
 	var entries = make(chan *Entry, 10)
@@ -50,13 +62,16 @@ func MapReduce(file io.Reader, parser StringParser, reducer Reducer) chan *Entry
 					sem <- false
 					return
 				}
-				entry, err := parser.ParseString(line)
-				if err == nil {
+				entry, err := parser.ParseString(line.Text)
+				if err == nil && entry != nil {
+					if tagger != nil {
+						tagger.Tag(entry, line)
+					}
 					// Write result Entry to the output channel. This will
 					// block goroutine runtime until channel is free to
 					// accept new item.
 					entries <- entry
-				} else {
+				} else if err != nil {
 					handleError(err)
 				}
 				// Increment semaphore to allow new mapper workers to spawn
@@ -73,38 +88,14 @@ func MapReduce(file io.Reader, parser StringParser, reducer Reducer) chan *Entry
 	go reducer.Reduce(entries, output)
 
 	go func() {
-		reader := bufio.NewReader(file)
-		line, err := readLine(reader)
-		for err == nil {
-			// Read next line from the file and feed mapper routines.
-			lines <- line
-			line, err = readLine(reader)
-		}
+		// Read lines from the input and feed mapper routines.
+		err := input.ReadLines(lines)
 		close(lines)
 
-		if err != nil && err != io.EOF {
+		if err != nil {
 			handleError(err)
 		}
 	}()
 
 	return output
 }
-
-func readLine(reader *bufio.Reader) (string, error) {
-	line, isPrefix, err := reader.ReadLine()
-	if err != nil {
-		return "", err
-	}
-	if !isPrefix {
-		return string(line), nil
-	}
-	var buffer bytes.Buffer
-	_, err = buffer.Write(line)
-	for isPrefix && err == nil {
-		line, isPrefix, err = reader.ReadLine()
-		if err == nil {
-			_, err = buffer.Write(line)
-		}
-	}
-	return buffer.String(), err
-}
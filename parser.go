@@ -9,6 +9,10 @@ import (
 )
 
 // StringParser is the interface that wraps the ParseString method.
+//
+// ParseString may return a nil entry without an error for a line to be skipped,
+// e.g. MapperParser does so for entries dropped by its mappers. Callers must
+// skip such lines silently.
 type StringParser interface {
 	ParseString(line string) (entry *Entry, err error)
 }
@@ -0,0 +1,82 @@
+package gonx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrorLogTimeLayout is the timestamp layout of nginx error log.
+const ErrorLogTimeLayout = "2006/01/02 15:04:05"
+
+var (
+	errorLogRe        = regexp.MustCompile(`(?s)^(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`)
+	errorLogContextRe = regexp.MustCompile(`, (client|server|login|upstream|request|subrequest|host|referrer): `)
+)
+
+// ErrorLogParser implements the StringParser interface for nginx error_log
+// lines like
+//
+//	2024/01/02 03:04:05 [error] 123#0: *456 upstream timed out, client: 1.2.3.4, server: x, request: "GET / HTTP/1.1", host: "x"
+//
+// Entry fields are time, level, pid, tid, connection (empty if there is no
+// connection id), message and the context fields present, e.g. client, server,
+// request, upstream, host and referrer. Quotes of context values are removed.
+type ErrorLogParser struct{}
+
+// NewErrorLogParser returns a parser of nginx error log lines.
+func NewErrorLogParser() *ErrorLogParser {
+	return &ErrorLogParser{}
+}
+
+// ParseString parses an error log line. An error is returned if the line does
+// not start with the error log timestamp, level and process ids.
+func (p *ErrorLogParser) ParseString(line string) (*Entry, error) {
+	fields := errorLogRe.FindStringSubmatch(line)
+	if fields == nil {
+		return nil, fmt.Errorf("error log line '%v' does not match nginx error log format", line)
+	}
+	entry := NewEntry(Fields{
+		"time":       fields[1],
+		"level":      fields[2],
+		"pid":        fields[3],
+		"tid":        fields[4],
+		"connection": fields[5],
+	})
+
+	message := fields[6]
+	if loc := errorLogContextRe.FindStringIndex(message); loc != nil {
+		parseErrorLogContext(entry, message[loc[0]:])
+		message = message[:loc[0]]
+	}
+	entry.SetField("message", message)
+	return entry, nil
+}
+
+// parseErrorLogContext sets the `, key: value` pairs following the message.
+// Quoted values may contain anything but quotes, nginx escapes them.
+func parseErrorLogContext(entry *Entry, context string) {
+	for context != "" {
+		loc := errorLogContextRe.FindStringSubmatchIndex(context)
+		if loc == nil || loc[0] != 0 {
+			return
+		}
+		key := context[loc[2]:loc[3]]
+		context = context[loc[1]:]
+
+		var value string
+		if strings.HasPrefix(context, `"`) {
+			end := strings.IndexByte(context[1:], '"')
+			if end < 0 {
+				end = len(context) - 1
+			}
+			value = context[1 : end+1]
+			context = context[min(end+2, len(context)):]
+		} else if next := errorLogContextRe.FindStringIndex(context); next != nil {
+			value, context = context[:next[0]], context[next[0]:]
+		} else {
+			value, context = context, ""
+		}
+		entry.SetField(key, value)
+	}
+}
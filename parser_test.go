@@ -98,5 +98,36 @@ func TestParser(t *testing.T) {
 			So(err, ShouldBeNil)
 			So(parser.Format, ShouldEqual, expected)
 		})
+
+		Convey("Nginx error log parser", func() {
+			parser := NewErrorLogParser()
+
+			entry, err := parser.ParseString(`2024/01/02 03:04:05 [error] 123#0: *456 upstream timed out (110: Connection timed out) while reading response header from upstream, client: 1.2.3.4, server: example.com, request: "GET /api?a=1, b HTTP/1.1", upstream: "http://127.0.0.1:8080/api?a=1, b", host: "example.com"`)
+			So(err, ShouldBeNil)
+			So(entry.Fields, ShouldResemble, Fields{
+				"time":       "2024/01/02 03:04:05",
+				"level":      "error",
+				"pid":        "123",
+				"tid":        "0",
+				"connection": "456",
+				"message":    "upstream timed out (110: Connection timed out) while reading response header from upstream",
+				"client":     "1.2.3.4",
+				"server":     "example.com",
+				"request":    "GET /api?a=1, b HTTP/1.1",
+				"upstream":   "http://127.0.0.1:8080/api?a=1, b",
+				"host":       "example.com",
+			})
+			t, err := ParseTime(entry.Fields["time"].(string))
+			So(err, ShouldBeNil)
+			So(t.Hour(), ShouldEqual, 3)
+
+			entry, err = parser.ParseString("2024/01/02 03:04:05 [notice] 1#1: signal process started")
+			So(err, ShouldBeNil)
+			So(entry.Fields["connection"], ShouldEqual, "")
+			So(entry.Fields["message"], ShouldEqual, "signal process started")
+
+			_, err = parser.ParseString(`1.2.3.4 - - [02/Jan/2024:03:04:05 +0000] "GET / HTTP/1.1" 200`)
+			So(err, ShouldNotBeNil)
+		})
 	})
 }
@@ -6,7 +6,7 @@ import (
 
 // Reader is a log file reader. Use specific constructors to create it.
 type Reader struct {
-	file    io.Reader
+	input   Input
 	parser  StringParser
 	entries chan *Entry
 }
@@ -18,8 +18,14 @@ func NewReader(logFile io.Reader, format string) *Reader {
 
 // NewParserReader creates a reader with the given parser
 func NewParserReader(logFile io.Reader, parser StringParser) *Reader {
+	return NewInputReader(NewReaderInput(logFile, ""), parser)
+}
+
+// NewInputReader creates a reader of the given Input with the parser, e.g. to
+// read a list of files with NewFiles or NewGlob.
+func NewInputReader(input Input, parser StringParser) *Reader {
 	return &Reader{
-		file:   logFile,
+		input:  input,
 		parser: parser,
 	}
 }
@@ -31,17 +37,14 @@ func NewNginxReader(logFile io.Reader, nginxConf io.Reader, formatName string) (
 	if err != nil {
 		return nil, err
 	}
-	reader = &Reader{
-		file:   logFile,
-		parser: parser,
-	}
+	reader = NewParserReader(logFile, parser)
 	return
 }
 
 // Read next parsed Entry from the log file. Return EOF if there are no Entries to read.
 func (r *Reader) Read() (entry *Entry, err error) {
 	if r.entries == nil {
-		r.entries = MapReduce(r.file, r.parser, new(ReadAll))
+		r.entries = MapReduceInput(r.input, r.parser, new(ReadAll))
 	}
 	entry, ok := <-r.entries
 	if !ok {
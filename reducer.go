@@ -26,29 +26,79 @@ func (r *ReadAll) Reduce(input chan *Entry, output chan *Entry) {
 	close(output)
 }
 
+// Accumulator is a partial aggregation state of a single reducer. It is fed
+// synchronously with Add, can absorb another state of the same kind with Merge
+// and reports aggregated values with Result.
+//
+// Accumulators are not safe for concurrent use.
+type Accumulator interface {
+	Add(entry *Entry)
+	Merge(other Accumulator)
+	Result() *Entry
+}
+
+// Aggregator is a Reducer which state can be kept in an Accumulator. Init
+// returns a new empty state. Reducers implementing it are computed without
+// spawning goroutines by Chain and GroupBy.
+type Aggregator interface {
+	Reducer
+	Init() Accumulator
+}
+
+// reduceAccumulator feeds accumulator with the input channel Entries and writes
+// the result to the output channel.
+func reduceAccumulator(acc Accumulator, input chan *Entry, output chan *Entry) {
+	for entry := range input {
+		acc.Add(entry)
+	}
+	output <- acc.Result()
+	close(output)
+}
+
 // Count implements the Reducer interface to count entries
 type Count struct {
 	Label string
 }
 
+// Init implements the Aggregator interface.
+func (r *Count) Init() Accumulator {
+	acc := &countAccumulator{label: r.label()}
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
+func (r *Count) label() string {
+	if r.Label == "" {
+		return "count"
+	}
+	return r.Label
+}
+
 // Reduce simply counts entries and write a sum to the output channel
 func (r *Count) Reduce(input chan *Entry, output chan *Entry) {
-	var count uint64
-	for {
-		_, ok := <-input
-		if !ok {
-			break
-		}
-		count++
+	reduceAccumulator(r.Init(), input, output)
+}
+
+type countAccumulator struct {
+	stateCodec
+	label string
+	state struct {
+		Count uint64 `json:"count"`
 	}
+}
+
+func (a *countAccumulator) Add(entry *Entry) {
+	a.state.Count++
+}
+
+func (a *countAccumulator) Merge(other Accumulator) {
+	a.state.Count += other.(*countAccumulator).state.Count
+}
+
+func (a *countAccumulator) Result() *Entry {
 	entry := NewEmptyEntry()
-	if r.Label != "" {
-		entry.SetUintField(r.Label, count)
-	} else {
-		entry.SetUintField("count", count)
-	}
-	output <- entry
-	close(output)
+	entry.SetUintField(a.label, a.state.Count)
+	return entry
 }
 
 // Sum implements the Reducer interface for summarize Entry values for the given fields
@@ -56,23 +106,48 @@ type Sum struct {
 	Fields map[string]string
 }
 
+// Init implements the Aggregator interface.
+func (r *Sum) Init() Accumulator {
+	acc := &sumAccumulator{fields: r.Fields}
+	acc.state.Sum = make(map[string]float64)
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
 // Reduce summarizes given Entry fields and return a map with result for each field.
 func (r *Sum) Reduce(input chan *Entry, output chan *Entry) {
-	sum := make(map[string]float64)
-	for entry := range input {
-		for label, name := range r.Fields {
-			val, err := entry.FloatField(name)
-			if err == nil {
-				sum[label] += val
-			}
+	reduceAccumulator(r.Init(), input, output)
+}
+
+type sumAccumulator struct {
+	stateCodec
+	fields map[string]string
+	state  struct {
+		Sum map[string]float64 `json:"sum"`
+	}
+}
+
+func (a *sumAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.FloatField(name)
+		if err == nil {
+			a.state.Sum[label] += val
 		}
 	}
+}
+
+func (a *sumAccumulator) Merge(other Accumulator) {
+	for label, val := range other.(*sumAccumulator).state.Sum {
+		a.state.Sum[label] += val
+	}
+}
+
+func (a *sumAccumulator) Result() *Entry {
 	entry := NewEmptyEntry()
-	for name, val := range sum {
+	for name, val := range a.state.Sum {
 		entry.SetFloatField(name, val)
 	}
-	output <- entry
-	close(output)
+	return entry
 }
 
 // Avg implements the Reducer interface for average entries values calculation
@@ -80,26 +155,56 @@ type Avg struct {
 	Fields map[string]string
 }
 
+// Init implements the Aggregator interface.
+func (r *Avg) Init() Accumulator {
+	acc := &avgAccumulator{fields: r.Fields}
+	acc.state.Sum = make(map[string]float64)
+	acc.state.Count = make(map[string]uint64)
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
 // Reduce calculates the average value for input channel Entries, using configured Fields
 // of the struct. Write result to the output channel as map[string]float64
 func (r *Avg) Reduce(input chan *Entry, output chan *Entry) {
-	avg := make(map[string]float64)
-	count := 0.0
-	for entry := range input {
-		for label, name := range r.Fields {
-			val, err := entry.FloatField(name)
-			if err == nil {
-				avg[label] = (avg[label]*count + val) / (count + 1)
-			}
+	reduceAccumulator(r.Init(), input, output)
+}
+
+// avgAccumulator keeps a sum and a number of values for each field, so two
+// partial averages could be merged without precision loss.
+type avgAccumulator struct {
+	stateCodec
+	fields map[string]string
+	state  struct {
+		Sum   map[string]float64 `json:"sum"`
+		Count map[string]uint64  `json:"count"`
+	}
+}
+
+func (a *avgAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.FloatField(name)
+		if err == nil {
+			a.state.Sum[label] += val
+			a.state.Count[label]++
 		}
-		count++
 	}
+}
+
+func (a *avgAccumulator) Merge(other Accumulator) {
+	o := other.(*avgAccumulator)
+	for label, val := range o.state.Sum {
+		a.state.Sum[label] += val
+		a.state.Count[label] += o.state.Count[label]
+	}
+}
+
+func (a *avgAccumulator) Result() *Entry {
 	entry := NewEmptyEntry()
-	for name, val := range avg {
-		entry.SetFloatField(name, val)
+	for name, val := range a.state.Sum {
+		entry.SetFloatField(name, val/float64(a.state.Count[name]))
 	}
-	output <- entry
-	close(output)
+	return entry
 }
 
 // Min implements the Reducer interface for min values calculation
@@ -107,26 +212,15 @@ type Min struct {
 	Fields map[string]string
 }
 
+// Init implements the Aggregator interface.
+func (r *Min) Init() Accumulator {
+	return newExtremumAccumulator(r.Fields, func(a, b float64) bool { return a < b })
+}
+
 // Reduce calculates the min values for input channel Entries, using configured Fields
 // of the struct. Write result to the output channel as map[string]float64
 func (r *Min) Reduce(input chan *Entry, output chan *Entry) {
-	min := make(map[string]float64)
-	for entry := range input {
-		for label, name := range r.Fields {
-			val, err := entry.FloatField(name)
-			if err == nil {
-				if val < min[label] || min[label] == 0 {
-					min[label] = val
-				}
-			}
-		}
-	}
-	entry := NewEmptyEntry()
-	for name, val := range min {
-		entry.SetFloatField(name, val)
-	}
-	output <- entry
-	close(output)
+	reduceAccumulator(r.Init(), input, output)
 }
 
 // Max implements the Reducer interface for min values calculation
@@ -134,26 +228,62 @@ type Max struct {
 	Fields map[string]string
 }
 
+// Init implements the Aggregator interface.
+func (r *Max) Init() Accumulator {
+	return newExtremumAccumulator(r.Fields, func(a, b float64) bool { return a > b })
+}
+
 // Reduce calculates the min values for input channel Entries, using configured Fields
 // of the struct. Write result to the output channel as map[string]float64
 func (r *Max) Reduce(input chan *Entry, output chan *Entry) {
-	max := make(map[string]float64)
-	for entry := range input {
-		for label, name := range r.Fields {
-			val, err := entry.FloatField(name)
-			if err == nil {
-				if val > max[label] {
-					max[label] = val
-				}
-			}
+	reduceAccumulator(r.Init(), input, output)
+}
+
+// extremumAccumulator keeps the first value for each field according to the
+// less function. It is shared by Min and Max.
+type extremumAccumulator struct {
+	stateCodec
+	fields map[string]string
+	less   func(a, b float64) bool
+	state  struct {
+		Values map[string]float64 `json:"values"`
+	}
+}
+
+func newExtremumAccumulator(fields map[string]string, less func(a, b float64) bool) *extremumAccumulator {
+	acc := &extremumAccumulator{fields: fields, less: less}
+	acc.state.Values = make(map[string]float64)
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
+func (a *extremumAccumulator) add(label string, val float64) {
+	if cur, ok := a.state.Values[label]; !ok || a.less(val, cur) {
+		a.state.Values[label] = val
+	}
+}
+
+func (a *extremumAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.FloatField(name)
+		if err == nil {
+			a.add(label, val)
 		}
 	}
+}
+
+func (a *extremumAccumulator) Merge(other Accumulator) {
+	for label, val := range other.(*extremumAccumulator).state.Values {
+		a.add(label, val)
+	}
+}
+
+func (a *extremumAccumulator) Result() *Entry {
 	entry := NewEmptyEntry()
-	for name, val := range max {
+	for name, val := range a.state.Values {
 		entry.SetFloatField(name, val)
 	}
-	output <- entry
-	close(output)
+	return entry
 }
 
 // Chain implements the Reducer interface for chaining other reducers
@@ -162,12 +292,15 @@ type Chain struct {
 	reducers []Reducer
 }
 
-// NewChain creates a new chain of Reducers
+// NewChain creates a new chain of Reducers. Filters and Mappers are applied to
+// entries in the given order before passing them to other reducers.
 func NewChain(reducers ...Reducer) *Chain {
 	chain := new(Chain)
 	for _, r := range reducers {
 		if f, ok := interface{}(r).(Filter); ok {
 			chain.filters = append(chain.filters, f)
+		} else if m, ok := r.(Mapper); ok {
+			chain.filters = append(chain.filters, mapperFilter{m})
 		} else {
 			chain.reducers = append(chain.reducers, r)
 		}
@@ -175,8 +308,32 @@ func NewChain(reducers ...Reducer) *Chain {
 	return chain
 }
 
+// Init implements the Aggregator interface. It returns nil if any of chained
+// reducers does not implement Aggregator.
+func (r *Chain) Init() Accumulator {
+	acc := &chainAccumulator{
+		filters: r.filters,
+		subs:    make([]Accumulator, len(r.reducers)),
+	}
+	for i, reducer := range r.reducers {
+		aggregator, ok := reducer.(Aggregator)
+		if !ok {
+			return nil
+		}
+		if acc.subs[i] = aggregator.Init(); acc.subs[i] == nil {
+			return nil
+		}
+	}
+	return acc
+}
+
 // Reduce applies a chain of reducers to the input channel of entries and merge results
 func (r *Chain) Reduce(input chan *Entry, output chan *Entry) {
+	if acc := r.Init(); acc != nil {
+		reduceAccumulator(acc, input, output)
+		return
+	}
+
 	// Make input and output channel for each reducer
 	subInput := make([]chan *Entry, len(r.reducers))
 	subOutput := make([]chan *Entry, len(r.reducers))
@@ -188,13 +345,7 @@ func (r *Chain) Reduce(input chan *Entry, output chan *Entry) {
 
 	// Read reducer master input channel
 	for entry := range input {
-
-		for _, f := range r.filters {
-			entry = f.Filter(entry)
-			if entry == nil {
-				break
-			}
-		}
+		entry = applyFilters(r.filters, entry)
 		// Publish input entry for each sub-reducers to process
 		if entry != nil {
 			for _, sub := range subInput {
@@ -216,11 +367,66 @@ func (r *Chain) Reduce(input chan *Entry, output chan *Entry) {
 	close(output)
 }
 
+// applyFilters returns the entry if it meets all filters conditions, otherwise nil.
+// The entry is copied before the first mapper modifies it.
+func applyFilters(filters []Filter, entry *Entry) *Entry {
+	copied := false
+	for _, f := range filters {
+		if _, ok := f.(mapperFilter); ok && !copied {
+			entry, copied = entry.copy(), true
+		}
+		entry = f.Filter(entry)
+		if entry == nil {
+			break
+		}
+	}
+	return entry
+}
+
+type chainAccumulator struct {
+	filters []Filter
+	subs    []Accumulator
+}
+
+func (a *chainAccumulator) Add(entry *Entry) {
+	if entry = applyFilters(a.filters, entry); entry == nil {
+		return
+	}
+	for _, sub := range a.subs {
+		sub.Add(entry)
+	}
+}
+
+func (a *chainAccumulator) Merge(other Accumulator) {
+	for i, sub := range other.(*chainAccumulator).subs {
+		a.subs[i].Merge(sub)
+	}
+}
+
+func (a *chainAccumulator) Result() *Entry {
+	entry := NewEmptyEntry()
+	for _, sub := range a.subs {
+		entry.Merge(sub.Result())
+	}
+	return entry
+}
+
+// DefaultOtherGroup is the value of grouping fields for entries which do not
+// fit into GroupBy MaxGroups limit.
+const DefaultOtherGroup = "other"
+
 // GroupBy implements the Reducer interface to apply other reducers and get data grouped by
 // given fields.
+//
+// When all of the reducers implement Aggregator, each group is aggregated
+// in place without any extra goroutines. Set MaxGroups to limit the number of
+// distinct groups, entries of any new group above the limit are accounted in a
+// single group with all Fields set to Other (DefaultOtherGroup if empty).
 type GroupBy struct {
-	Fields   []string
-	reducers []Reducer
+	Fields    []string
+	MaxGroups int
+	Other     string
+	reducers  []Reducer
 }
 
 // NewGroupBy creates a new GroupBy Reducer
@@ -231,8 +437,55 @@ func NewGroupBy(fields []string, reducers ...Reducer) *GroupBy {
 	}
 }
 
+// limited reports whether a new group could not be added to size existing ones.
+func (r *GroupBy) limited(size int) bool {
+	return r.MaxGroups > 0 && size >= r.MaxGroups
+}
+
+// otherKey returns the key of the group for entries above MaxGroups limit. It
+// never collides with a FieldsHash result.
+func (r *GroupBy) otherKey() string {
+	return "\x00" + r.otherLabel()
+}
+
+// otherFields returns grouping fields of the group for entries above MaxGroups limit.
+func (r *GroupBy) otherFields() *Entry {
+	partial := NewEmptyEntry()
+	for _, name := range r.Fields {
+		partial.SetField(name, r.otherLabel())
+	}
+	return partial
+}
+
+func (r *GroupBy) otherLabel() string {
+	if r.Other == "" {
+		return DefaultOtherGroup
+	}
+	return r.Other
+}
+
 // Reduce applies related reducers and group data by Fields.
 func (r *GroupBy) Reduce(input chan *Entry, output chan *Entry) {
+	state := r.State()
+	if state == nil {
+		r.reduceAsync(input, output)
+		return
+	}
+
+	for entry := range input {
+		state.Add(entry)
+	}
+	for _, entry := range state.Results() {
+		output <- entry
+	}
+	close(output)
+}
+
+// reduceAsync runs a reducers chain in a separate goroutine for each group.
+// It is used for reducers which do not implement Aggregator.
+func (r *GroupBy) reduceAsync(input chan *Entry, output chan *Entry) {
+	chain := NewChain(r.reducers...)
+	var keys []string
 	subInput := make(map[string]chan *Entry)
 	subOutput := make(map[string]chan *Entry)
 
@@ -241,17 +494,25 @@ func (r *GroupBy) Reduce(input chan *Entry, output chan *Entry) {
 	for entry := range input {
 		key := entry.FieldsHash(r.Fields)
 		if _, ok := subInput[key]; !ok {
-			subInput[key] = make(chan *Entry, cap(input))
-			subOutput[key] = make(chan *Entry, cap(output)+1)
-			subOutput[key] <- entry.Partial(r.Fields)
-			go NewChain(r.reducers...).Reduce(subInput[key], subOutput[key])
+			partial := entry.Partial
+			if r.limited(len(subInput)) {
+				key, partial = r.otherKey(), func([]string) *Entry { return r.otherFields() }
+			}
+			if _, ok := subInput[key]; !ok {
+				subInput[key] = make(chan *Entry, cap(input))
+				subOutput[key] = make(chan *Entry, cap(output)+1)
+				subOutput[key] <- partial(r.Fields)
+				keys = append(keys, key)
+				go chain.Reduce(subInput[key], subOutput[key])
+			}
 		}
 		subInput[key] <- entry
 	}
 	for _, ch := range subInput {
 		close(ch)
 	}
-	for _, ch := range subOutput {
+	for _, key := range keys {
+		ch := subOutput[key]
 		entry := <-ch
 		entry.Merge(<-ch)
 		output <- entry
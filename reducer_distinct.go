@@ -0,0 +1,64 @@
+package gonx
+
+// Distinct implements the Reducer interface to count distinct values of the given
+// fields. All the values are kept in memory, so the result is exact and partial
+// states are merged with no loss.
+type Distinct struct {
+	Fields map[string]string
+}
+
+// Init implements the Aggregator interface.
+func (r *Distinct) Init() Accumulator {
+	acc := &distinctAccumulator{fields: r.Fields}
+	acc.state.Values = make(map[string]map[string]bool)
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
+// Reduce counts distinct values for input channel Entries, using configured Fields
+// of the struct. Write result to the output channel as map[string]uint64
+func (r *Distinct) Reduce(input chan *Entry, output chan *Entry) {
+	reduceAccumulator(r.Init(), input, output)
+}
+
+type distinctAccumulator struct {
+	stateCodec
+	fields map[string]string
+	state  struct {
+		Values map[string]map[string]bool `json:"values"`
+	}
+}
+
+func (a *distinctAccumulator) add(label, val string) {
+	values, ok := a.state.Values[label]
+	if !ok {
+		values = make(map[string]bool)
+		a.state.Values[label] = values
+	}
+	values[val] = true
+}
+
+func (a *distinctAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.StringField(name)
+		if err == nil {
+			a.add(label, val)
+		}
+	}
+}
+
+func (a *distinctAccumulator) Merge(other Accumulator) {
+	for label, values := range other.(*distinctAccumulator).state.Values {
+		for val := range values {
+			a.add(label, val)
+		}
+	}
+}
+
+func (a *distinctAccumulator) Result() *Entry {
+	entry := NewEmptyEntry()
+	for name, values := range a.state.Values {
+		entry.SetUintField(name, uint64(len(values)))
+	}
+	return entry
+}
@@ -1,6 +1,8 @@
 package gonx
 
 import (
+	"encoding/json"
+	"fmt"
 	"math"
 )
 
@@ -36,6 +38,25 @@ func NewBin(bucketSizes ...float64) *Bin {
 	return histogram
 }
 
+// clone returns an empty Bin with the same buckets bounds.
+func (h *Bin) clone() *Bin {
+	c := &Bin{Buckets: make([]Bucket, len(h.Buckets))}
+	for i, bucket := range h.Buckets {
+		c.Buckets[i] = Bucket{LowerBound: bucket.LowerBound, UpperBound: bucket.UpperBound}
+	}
+	return c
+}
+
+// merge adds counters of the other Bin with the same buckets bounds.
+func (h *Bin) merge(other *Bin) {
+	for i := range h.Buckets {
+		if i < len(other.Buckets) {
+			h.Buckets[i].Count += other.Buckets[i].Count
+		}
+	}
+	h.Total += other.Total
+}
+
 func (h *Bin) Add(value float64) {
 	for i, bucket := range h.Buckets {
 		if value >= bucket.LowerBound && value < bucket.UpperBound {
@@ -102,6 +123,19 @@ type ReducerHistogram struct {
 	Bins   map[string]*Bin
 }
 
+// Init implements the Aggregator interface. Each accumulator gets its own
+// empty copy of the configured Bins.
+func (r *ReducerHistogram) Init() Accumulator {
+	acc := &histogramAccumulator{
+		fields: r.Fields,
+		bins:   make(map[string]*Bin, len(r.Bins)),
+	}
+	for name, bin := range r.Bins {
+		acc.bins[name] = bin.clone()
+	}
+	return acc
+}
+
 // Reduce calculates the min values for input channel Entries, using configured Fields
 // of the struct. Write result to the output channel as map[string]float64
 func (r *ReducerHistogram) Reduce(input chan *Entry, output chan *Entry) {
@@ -109,20 +143,37 @@ func (r *ReducerHistogram) Reduce(input chan *Entry, output chan *Entry) {
 		close(output)
 		return
 	}
+	reduceAccumulator(r.Init(), input, output)
+}
+
+type histogramAccumulator struct {
+	fields map[string]string
+	bins   map[string]*Bin
+}
 
-	for entry := range input {
-		for label, name := range r.Fields {
-			val, err := entry.FloatField(name)
-			if err == nil {
-				if _, ok := r.Bins[label]; !ok {
-					continue
-				}
-				r.Bins[label].Add(val)
+func (a *histogramAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.FloatField(name)
+		if err == nil {
+			if _, ok := a.bins[label]; !ok {
+				continue
 			}
+			a.bins[label].Add(val)
 		}
 	}
+}
+
+func (a *histogramAccumulator) Merge(other Accumulator) {
+	for name, bin := range other.(*histogramAccumulator).bins {
+		if _, ok := a.bins[name]; ok {
+			a.bins[name].merge(bin)
+		}
+	}
+}
+
+func (a *histogramAccumulator) Result() *Entry {
 	entry := NewEmptyEntry()
-	for name, bin := range r.Bins {
+	for name, bin := range a.bins {
 		histogram := NewEmptyEntry()
 		histogram.SetFloatField("p5", bin.Percentile(5))
 		histogram.SetFloatField("p10", bin.Percentile(10))
@@ -135,6 +186,69 @@ func (r *ReducerHistogram) Reduce(input chan *Entry, output chan *Entry) {
 		histogram.SetFloatField("total", float64(bin.Total))
 		entry.SetEntryField(name, histogram)
 	}
-	output <- entry
-	close(output)
+	return entry
+}
+
+// histogramState is a serialized representation of the histogram buckets
+// counters. Buckets bounds are taken from the reducer configuration.
+type histogramState struct {
+	Counts map[string][]int `json:"counts"`
+}
+
+func (a *histogramAccumulator) state() histogramState {
+	state := histogramState{Counts: make(map[string][]int, len(a.bins))}
+	for name, bin := range a.bins {
+		counts := make([]int, len(bin.Buckets))
+		for i, bucket := range bin.Buckets {
+			counts[i] = bucket.Count
+		}
+		state.Counts[name] = counts
+	}
+	return state
+}
+
+func (a *histogramAccumulator) setState(state histogramState) error {
+	for name, counts := range state.Counts {
+		bin, ok := a.bins[name]
+		if !ok {
+			return fmt.Errorf("histogram '%v' is not configured", name)
+		}
+		if len(counts) != len(bin.Buckets) {
+			return fmt.Errorf("histogram '%v' has %d buckets, state has %d", name, len(bin.Buckets), len(counts))
+		}
+		bin.Total = 0
+		for i, count := range counts {
+			bin.Buckets[i].Count = count
+			bin.Total += count
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a *histogramAccumulator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.state())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *histogramAccumulator) UnmarshalJSON(data []byte) error {
+	var state histogramState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	return a.setState(state)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (a *histogramAccumulator) MarshalBinary() ([]byte, error) {
+	return gobEncode(a.state())
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (a *histogramAccumulator) UnmarshalBinary(data []byte) error {
+	var state histogramState
+	if err := gobDecode(data, &state); err != nil {
+		return err
+	}
+	return a.setState(state)
 }
@@ -0,0 +1,211 @@
+package gonx
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultQuantiles are calculated by Quantile reducer if none are configured.
+var DefaultQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// DefaultQuantileAccuracy is the relative accuracy of Quantile reducer if none
+// is configured.
+const DefaultQuantileAccuracy = 0.01
+
+// Quantile implements the Reducer interface for quantiles estimation. Values are
+// counted in logarithmic buckets, so the estimation relative error is not greater
+// than Accuracy, and partial states are merged with no additional loss.
+//
+// Result is a nested Entry for each field with "p50", "p99.9", etc. values for
+// Quantiles in the range [0, 1] and the "total" number of values. Quantiles out
+// of the range are reported to ErrorHandler once and skipped.
+type Quantile struct {
+	Fields    map[string]string
+	Quantiles []float64
+	Accuracy  float64
+
+	once  sync.Once
+	valid []float64
+}
+
+// quantiles returns the configured quantiles in range, validated on first use
+// as Init is called for each group of GroupBy.
+func (r *Quantile) quantiles() []float64 {
+	r.once.Do(func() {
+		quantiles := r.Quantiles
+		if len(quantiles) == 0 {
+			quantiles = DefaultQuantiles
+		}
+		r.valid = make([]float64, 0, len(quantiles))
+		for _, q := range quantiles {
+			if q < 0 || q > 1 || math.IsNaN(q) {
+				handleError(fmt.Errorf("quantile %v is out of range [0, 1]", q))
+				continue
+			}
+			r.valid = append(r.valid, q)
+		}
+	})
+	return r.valid
+}
+
+// Init implements the Aggregator interface.
+func (r *Quantile) Init() Accumulator {
+	accuracy := r.Accuracy
+	if accuracy <= 0 || accuracy >= 1 {
+		accuracy = DefaultQuantileAccuracy
+	}
+	acc := &quantileAccumulator{
+		fields:    r.Fields,
+		quantiles: r.quantiles(),
+		gamma:     (1 + accuracy) / (1 - accuracy),
+	}
+	acc.state.Sketches = make(map[string]*sketch)
+	acc.stateCodec = stateCodec{&acc.state}
+	return acc
+}
+
+// Reduce estimates quantiles of the input channel Entries values, using configured
+// Fields of the struct.
+func (r *Quantile) Reduce(input chan *Entry, output chan *Entry) {
+	reduceAccumulator(r.Init(), input, output)
+}
+
+// sketch counts values in logarithmic buckets. Bucket i contains values in
+// range (gamma^(i-1), gamma^i].
+type sketch struct {
+	Positive map[int]uint64 `json:"positive"`
+	Negative map[int]uint64 `json:"negative"`
+	Zero     uint64         `json:"zero"`
+	Total    uint64         `json:"total"`
+}
+
+// minSketchValue is the smallest absolute value which is not counted as zero.
+const minSketchValue = 1e-9
+
+func newSketch() *sketch {
+	return &sketch{
+		Positive: make(map[int]uint64),
+		Negative: make(map[int]uint64),
+	}
+}
+
+func (s *sketch) add(val, gamma float64) {
+	switch {
+	case val > minSketchValue:
+		s.Positive[int(math.Ceil(math.Log(val)/math.Log(gamma)))]++
+	case val < -minSketchValue:
+		s.Negative[int(math.Ceil(math.Log(-val)/math.Log(gamma)))]++
+	default:
+		s.Zero++
+	}
+	s.Total++
+}
+
+func (s *sketch) merge(other *sketch) {
+	for i, count := range other.Positive {
+		s.Positive[i] += count
+	}
+	for i, count := range other.Negative {
+		s.Negative[i] += count
+	}
+	s.Zero += other.Zero
+	s.Total += other.Total
+}
+
+func (s *sketch) quantile(q, gamma float64) float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	value := func(i int) float64 {
+		return 2 * math.Pow(gamma, float64(i)) / (gamma + 1)
+	}
+	rank := math.Min(math.Max(q, 0), 1) * float64(s.Total-1)
+	var count uint64
+
+	// Negative values in ascending order have descending bucket indexes
+	negative := sortedKeys(s.Negative)
+	for j := len(negative) - 1; j >= 0; j-- {
+		count += s.Negative[negative[j]]
+		if float64(count) > rank {
+			return -value(negative[j])
+		}
+	}
+	count += s.Zero
+	if float64(count) > rank {
+		return 0
+	}
+	positive := sortedKeys(s.Positive)
+	for _, i := range positive {
+		count += s.Positive[i]
+		if float64(count) > rank {
+			return value(i)
+		}
+	}
+	// not reached for q in [0, 1], return the maximum value anyway
+	switch {
+	case len(positive) > 0:
+		return value(positive[len(positive)-1])
+	case s.Zero > 0:
+		return 0
+	}
+	return -value(negative[0])
+}
+
+func sortedKeys(m map[int]uint64) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+type quantileAccumulator struct {
+	stateCodec
+	fields    map[string]string
+	quantiles []float64
+	gamma     float64
+	state     struct {
+		Sketches map[string]*sketch `json:"sketches"`
+	}
+}
+
+func (a *quantileAccumulator) sketch(label string) *sketch {
+	s, ok := a.state.Sketches[label]
+	if !ok {
+		s = newSketch()
+		a.state.Sketches[label] = s
+	}
+	return s
+}
+
+func (a *quantileAccumulator) Add(entry *Entry) {
+	for label, name := range a.fields {
+		val, err := entry.FloatField(name)
+		if err == nil {
+			a.sketch(label).add(val, a.gamma)
+		}
+	}
+}
+
+func (a *quantileAccumulator) Merge(other Accumulator) {
+	for label, s := range other.(*quantileAccumulator).state.Sketches {
+		a.sketch(label).merge(s)
+	}
+}
+
+func (a *quantileAccumulator) Result() *Entry {
+	entry := NewEmptyEntry()
+	for name, s := range a.state.Sketches {
+		quantiles := NewEmptyEntry()
+		for _, q := range a.quantiles {
+			quantiles.SetFloatField("p"+strconv.FormatFloat(q*100, 'f', -1, 64), s.quantile(q, a.gamma))
+		}
+		quantiles.SetFloatField("total", float64(s.Total))
+		entry.SetEntryField(name, quantiles)
+	}
+	return entry
+}
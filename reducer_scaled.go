@@ -0,0 +1,127 @@
+package gonx
+
+import "math"
+
+// Scaled implements the Reducer interface to multiply results of other reducers
+// by the Factor. It is used to scale Count and Sum of sampled entries back up,
+// e.g.
+//
+//	sample := &Sample{Rate: 0.01, Fields: []string{"remote_addr"}}
+//	NewChain(sample, NewScaled(sample.Scale(), &Count{}, &Sum{...}))
+//
+// Only results of Count and Sum are scaled, results of other reducers, like
+// Avg or Max, are passed through unchanged.
+type Scaled struct {
+	Factor float64
+	chain  *Chain
+	fields map[string]bool
+}
+
+// NewScaled creates a new Scaled reducer for the chain of reducers.
+func NewScaled(factor float64, reducers ...Reducer) *Scaled {
+	fields := make(map[string]bool)
+	for _, reducer := range reducers {
+		switch reducer := reducer.(type) {
+		case *Count:
+			fields[reducer.label()] = true
+		case *Sum:
+			for label := range reducer.Fields {
+				fields[label] = true
+			}
+		}
+	}
+	return &Scaled{Factor: factor, chain: NewChain(reducers...), fields: fields}
+}
+
+// Init implements the Aggregator interface. It returns nil if any of the
+// reducers does not implement Aggregator.
+func (r *Scaled) Init() Accumulator {
+	acc := r.chain.Init()
+	if acc == nil {
+		return nil
+	}
+	return &scaledAccumulator{acc: acc, factor: r.Factor, fields: r.fields}
+}
+
+// Reduce calculates results of the reducers and multiplies them by the Factor.
+func (r *Scaled) Reduce(input chan *Entry, output chan *Entry) {
+	if acc := r.Init(); acc != nil {
+		reduceAccumulator(acc, input, output)
+		return
+	}
+	subOutput := make(chan *Entry, 1)
+	go r.chain.Reduce(input, subOutput)
+	output <- scaleEntry(<-subOutput, r.Factor, r.fields)
+	close(output)
+}
+
+type scaledAccumulator struct {
+	acc    Accumulator
+	factor float64
+	fields map[string]bool
+}
+
+func (a *scaledAccumulator) Add(entry *Entry) {
+	a.acc.Add(entry)
+}
+
+func (a *scaledAccumulator) Merge(other Accumulator) {
+	a.acc.Merge(other.(*scaledAccumulator).acc)
+}
+
+func (a *scaledAccumulator) Result() *Entry {
+	return scaleEntry(a.acc.Result(), a.factor, a.fields)
+}
+
+// scaleEntry multiplies the float and unsigned integer fields of the entry
+// given by name.
+func scaleEntry(entry *Entry, factor float64, fields map[string]bool) *Entry {
+	for name, value := range entry.Fields {
+		if !fields[name] {
+			continue
+		}
+		switch value := value.(type) {
+		case float64:
+			entry.SetFloatField(name, value*factor)
+		case uint64:
+			entry.SetUintField(name, uint64(math.Round(float64(value)*factor)))
+		}
+	}
+	return entry
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a *scaledAccumulator) MarshalJSON() ([]byte, error) {
+	state, err := subState(a.acc)
+	if err != nil {
+		return nil, err
+	}
+	return state.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *scaledAccumulator) UnmarshalJSON(data []byte) error {
+	state, err := subState(a.acc)
+	if err != nil {
+		return err
+	}
+	return state.UnmarshalJSON(data)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (a *scaledAccumulator) MarshalBinary() ([]byte, error) {
+	state, err := subState(a.acc)
+	if err != nil {
+		return nil, err
+	}
+	return state.MarshalBinary()
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (a *scaledAccumulator) UnmarshalBinary(data []byte) error {
+	state, err := subState(a.acc)
+	if err != nil {
+		return err
+	}
+	return state.UnmarshalBinary(data)
+}
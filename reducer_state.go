@@ -0,0 +1,321 @@
+package gonx
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// State is an Accumulator which can be serialized, so partial aggregates could be
+// calculated separately (e.g. for each log file or on different machines) and then
+// merged into the exact final result.
+//
+// Serialized data should be unmarshaled into an empty state returned by Init of
+// a reducer with the same configuration.
+type State interface {
+	Accumulator
+	json.Marshaler
+	json.Unmarshaler
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// stateCodec implements serialization methods of the State interface for
+// accumulators which keep the whole state in a single value.
+type stateCodec struct {
+	state any
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (c stateCodec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.state)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (c stateCodec) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, c.state)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (c stateCodec) MarshalBinary() ([]byte, error) {
+	return gobEncode(c.state)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (c stateCodec) UnmarshalBinary(data []byte) error {
+	return gobDecode(data, c.state)
+}
+
+func gobEncode(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecode(data []byte, value any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(value)
+}
+
+// subState returns the accumulator as a State or an error if it is not serializable.
+func subState(acc Accumulator) (State, error) {
+	state, ok := acc.(State)
+	if !ok {
+		return nil, fmt.Errorf("accumulator %T does not implement State", acc)
+	}
+	return state, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface. Chained states are
+// encoded as an array in the order of reducers.
+func (a *chainAccumulator) MarshalJSON() ([]byte, error) {
+	subs := make([]json.RawMessage, len(a.subs))
+	for i, sub := range a.subs {
+		state, err := subState(sub)
+		if err != nil {
+			return nil, err
+		}
+		if subs[i], err = state.MarshalJSON(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(subs)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *chainAccumulator) UnmarshalJSON(data []byte) error {
+	var subs []json.RawMessage
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return err
+	}
+	return a.unmarshalSubs(len(subs), func(i int, state State) error {
+		return state.UnmarshalJSON(subs[i])
+	})
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (a *chainAccumulator) MarshalBinary() ([]byte, error) {
+	subs := make([][]byte, len(a.subs))
+	for i, sub := range a.subs {
+		state, err := subState(sub)
+		if err != nil {
+			return nil, err
+		}
+		if subs[i], err = state.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return gobEncode(subs)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (a *chainAccumulator) UnmarshalBinary(data []byte) error {
+	var subs [][]byte
+	if err := gobDecode(data, &subs); err != nil {
+		return err
+	}
+	return a.unmarshalSubs(len(subs), func(i int, state State) error {
+		return state.UnmarshalBinary(subs[i])
+	})
+}
+
+func (a *chainAccumulator) unmarshalSubs(n int, unmarshal func(int, State) error) error {
+	if n != len(a.subs) {
+		return fmt.Errorf("chain state has %d reducers, expected %d", n, len(a.subs))
+	}
+	for i, sub := range a.subs {
+		state, err := subState(sub)
+		if err != nil {
+			return err
+		}
+		if err := unmarshal(i, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GroupState is a mergeable and serializable state of the GroupBy reducer. Use
+// GroupBy.State to create it.
+type GroupState struct {
+	group  *GroupBy
+	chain  *Chain
+	keys   []string
+	fields map[string]*Entry
+	accs   map[string]Accumulator
+}
+
+// groupStateItem is a serialized representation of a single group.
+type groupStateItem struct {
+	Key    string          `json:"key"`
+	Fields *Entry          `json:"fields"`
+	State  json.RawMessage `json:"state"`
+}
+
+// groupStateBinary is a binary representation of the GroupState.
+type groupStateBinary struct {
+	Keys   []string
+	Fields [][]byte
+	States [][]byte
+}
+
+// State returns an empty GroupState for the reducer or nil if any of its
+// reducers does not implement Aggregator.
+func (r *GroupBy) State() *GroupState {
+	chain := NewChain(r.reducers...)
+	if chain.Init() == nil {
+		return nil
+	}
+	return &GroupState{
+		group:  r,
+		chain:  chain,
+		fields: make(map[string]*Entry),
+		accs:   make(map[string]Accumulator),
+	}
+}
+
+// accumulator returns an accumulator for the group with given key, creating a new
+// one if necessary. Groups above the MaxGroups limit share the other group.
+func (s *GroupState) accumulator(key string, fields func() *Entry) Accumulator {
+	if acc, ok := s.accs[key]; ok {
+		return acc
+	}
+	if s.group.limited(len(s.accs)) {
+		key, fields = s.group.otherKey(), s.group.otherFields
+		if acc, ok := s.accs[key]; ok {
+			return acc
+		}
+	}
+	acc := s.chain.Init()
+	s.accs[key] = acc
+	s.fields[key] = fields()
+	s.keys = append(s.keys, key)
+	return acc
+}
+
+// Add accounts the entry in its group.
+func (s *GroupState) Add(entry *Entry) {
+	key := entry.FieldsHash(s.group.Fields)
+	s.accumulator(key, func() *Entry { return entry.Partial(s.group.Fields) }).Add(entry)
+}
+
+// Merge adds all groups of the other state of the same GroupBy configuration.
+func (s *GroupState) Merge(other *GroupState) {
+	for _, key := range other.keys {
+		s.merge(key, other.fields[key], other.accs[key])
+	}
+}
+
+func (s *GroupState) merge(key string, fields *Entry, acc Accumulator) {
+	s.accumulator(key, func() *Entry {
+		partial := NewEmptyEntry()
+		partial.Merge(fields)
+		return partial
+	}).Merge(acc)
+}
+
+// Results returns an Entry for each group in order of appearance. Each Entry
+// contains grouping fields and reducers results.
+func (s *GroupState) Results() []*Entry {
+	results := make([]*Entry, 0, len(s.keys))
+	for _, key := range s.keys {
+		entry := NewEmptyEntry()
+		entry.Merge(s.fields[key])
+		entry.Merge(s.accs[key].Result())
+		results = append(results, entry)
+	}
+	return results
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s *GroupState) MarshalJSON() ([]byte, error) {
+	items := make([]groupStateItem, len(s.keys))
+	for i, key := range s.keys {
+		state, err := subState(s.accs[key])
+		if err != nil {
+			return nil, err
+		}
+		items[i] = groupStateItem{Key: key, Fields: s.fields[key]}
+		if items[i].State, err = state.MarshalJSON(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Decoded groups are
+// merged into the state.
+func (s *GroupState) UnmarshalJSON(data []byte) error {
+	var items []groupStateItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		acc := s.chain.Init()
+		state, err := subState(acc)
+		if err != nil {
+			return err
+		}
+		if err := state.UnmarshalJSON(item.State); err != nil {
+			return err
+		}
+		if item.Fields == nil {
+			item.Fields = NewEmptyEntry()
+		}
+		s.merge(item.Key, item.Fields, acc)
+	}
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (s *GroupState) MarshalBinary() ([]byte, error) {
+	bin := groupStateBinary{
+		Keys:   s.keys,
+		Fields: make([][]byte, len(s.keys)),
+		States: make([][]byte, len(s.keys)),
+	}
+	for i, key := range s.keys {
+		state, err := subState(s.accs[key])
+		if err != nil {
+			return nil, err
+		}
+		if bin.Fields[i], err = json.Marshal(s.fields[key]); err != nil {
+			return nil, err
+		}
+		if bin.States[i], err = state.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return gobEncode(bin)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. Decoded
+// groups are merged into the state.
+func (s *GroupState) UnmarshalBinary(data []byte) error {
+	var bin groupStateBinary
+	if err := gobDecode(data, &bin); err != nil {
+		return err
+	}
+	if len(bin.Fields) != len(bin.Keys) || len(bin.States) != len(bin.Keys) {
+		return fmt.Errorf("malformed group state")
+	}
+	for i, key := range bin.Keys {
+		fields := NewEmptyEntry()
+		if err := json.Unmarshal(bin.Fields[i], fields); err != nil {
+			return err
+		}
+		acc := s.chain.Init()
+		state, err := subState(acc)
+		if err != nil {
+			return err
+		}
+		if err := state.UnmarshalBinary(bin.States[i]); err != nil {
+			return err
+		}
+		s.merge(key, fields, acc)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package gonx
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReducerState(t *testing.T) {
+	Convey("Test mergeable reducers state", t, func() {
+		first := []*Entry{
+			NewEntry(Fields{"host": "alpha.example.com", "foo": "1", "client": "a"}),
+			NewEntry(Fields{"host": "beta.example.com", "foo": "4", "client": "b"}),
+		}
+		second := []*Entry{
+			NewEntry(Fields{"host": "beta.example.com", "foo": "7", "client": "a"}),
+			NewEntry(Fields{"host": "gamma.example.com", "foo": "10", "client": "c"}),
+		}
+
+		reducer := NewChain(
+			&Count{},
+			&Sum{map[string]string{"sum": "foo"}},
+			&Avg{map[string]string{"avg": "foo"}},
+			&Min{map[string]string{"min": "foo"}},
+			&Max{map[string]string{"max": "foo"}},
+			&Distinct{map[string]string{"clients": "client"}},
+			&Quantile{Fields: map[string]string{"quantile": "foo"}, Quantiles: []float64{0, 1}},
+			&ReducerHistogram{Fields: map[string]string{"histogram": "foo"}, Bins: map[string]*Bin{"histogram": NewBin(0, 5, 10)}},
+		)
+
+		assertResult := func(result *Entry) {
+			count, err := result.FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 4)
+
+			value, err := result.FloatField("sum")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 22)
+
+			value, err = result.FloatField("avg")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 5.5)
+
+			value, err = result.FloatField("min")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 1)
+
+			value, err = result.FloatField("max")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 10)
+
+			value, err = result.FloatField("clients")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 3)
+
+			quantile, err := result.EntryField("quantile")
+			So(err, ShouldBeNil)
+			value, err = quantile.FloatField("p0")
+			So(err, ShouldBeNil)
+			So(value, ShouldAlmostEqual, 1, 0.01)
+			value, err = quantile.FloatField("p100")
+			So(err, ShouldBeNil)
+			So(value, ShouldAlmostEqual, 10, 0.1)
+
+			histogram, err := result.EntryField("histogram")
+			So(err, ShouldBeNil)
+			value, err = histogram.FloatField("total")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 4)
+		}
+
+		accumulate := func(entries []*Entry) State {
+			acc := reducer.Init()
+			for _, entry := range entries {
+				acc.Add(entry)
+			}
+			state, ok := acc.(State)
+			So(ok, ShouldBeTrue)
+			return state
+		}
+
+		Convey("Merge JSON serialized states", func() {
+			data, err := json.Marshal(accumulate(first))
+			So(err, ShouldBeNil)
+
+			state := reducer.Init().(State)
+			So(json.Unmarshal(data, state), ShouldBeNil)
+			state.Merge(accumulate(second))
+			assertResult(state.Result())
+		})
+
+		Convey("Merge binary serialized states", func() {
+			data, err := accumulate(second).MarshalBinary()
+			So(err, ShouldBeNil)
+
+			state := reducer.Init().(State)
+			So(state.UnmarshalBinary(data), ShouldBeNil)
+			state.Merge(accumulate(first))
+			assertResult(state.Result())
+		})
+
+		Convey("Reject state of other configuration", func() {
+			data, err := json.Marshal(accumulate(first))
+			So(err, ShouldBeNil)
+
+			state := NewChain(&Count{}).Init().(State)
+			So(json.Unmarshal(data, state), ShouldNotBeNil)
+		})
+
+		Convey("Merge group states", func() {
+			group := NewGroupBy([]string{"host"}, &Count{}, &Sum{map[string]string{"foo": "foo"}})
+			state := group.State()
+			for _, entry := range first {
+				state.Add(entry)
+			}
+			data, err := json.Marshal(state)
+			So(err, ShouldBeNil)
+
+			merged := group.State()
+			for _, entry := range second {
+				merged.Add(entry)
+			}
+			So(json.Unmarshal(data, merged), ShouldBeNil)
+
+			results := make(map[string]*Entry)
+			for _, result := range merged.Results() {
+				host, err := result.StringField("host")
+				So(err, ShouldBeNil)
+				results[host] = result
+			}
+			So(len(results), ShouldEqual, 3)
+
+			value, err := results["beta.example.com"].FloatField("foo")
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, 4+7)
+
+			count, err := results["beta.example.com"].FloatField("count")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 2)
+
+			data, err = merged.MarshalBinary()
+			So(err, ShouldBeNil)
+			restored := group.State()
+			So(restored.UnmarshalBinary(data), ShouldBeNil)
+			So(restored.Results(), ShouldResemble, merged.Results())
+		})
+	})
+}
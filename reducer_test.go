@@ -217,6 +217,61 @@ func TestReducer(t *testing.T) {
 				So(err, ShouldBeNil)
 				So(count, ShouldEqual, 2)
 			})
+
+			Convey("Group reducer with groups limit", func() {
+				reducer := NewGroupBy([]string{"host"}, new(Count))
+				reducer.MaxGroups = 1
+				reducer.Reduce(input, output)
+
+				resultMap := make(map[string]*Entry)
+				for result := range output {
+					value, err := result.StringField("host")
+					So(err, ShouldBeNil)
+					resultMap[value] = result
+				}
+				So(len(resultMap), ShouldEqual, 2)
+
+				count, err := resultMap["alpha.example.com"].FloatField("count")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+
+				count, err = resultMap[DefaultOtherGroup].FloatField("count")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 2)
+			})
+
+			Convey("Merge accumulators", func() {
+				reducer := NewChain(
+					&Avg{map[string]string{"foo": "foo"}},
+					&Min{map[string]string{"min": "foo"}},
+					&Max{map[string]string{"max": "foo"}},
+					&Count{},
+				)
+				first, second := reducer.Init(), reducer.Init()
+				So(first, ShouldNotBeNil)
+				first.Add(<-input)
+				for entry := range input {
+					second.Add(entry)
+				}
+				first.Merge(second)
+				result := first.Result()
+
+				value, err := result.FloatField("foo")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, (1+4+7)/total)
+
+				value, err = result.FloatField("min")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 1)
+
+				value, err = result.FloatField("max")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 7)
+
+				count, err := result.FloatField("count")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, total)
+			})
 		})
 	})
 }
@@ -267,15 +322,77 @@ func TestHistogramReducer(t *testing.T) {
 				// So(err, ShouldBeNil)
 				// So(value, ShouldEqual, 7)
 
-				valuestr, err := result.Field("foo")
+				value, err = entry.FloatField("mean")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 4)
+
+				value, err = entry.FloatField("p50")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 1)
+
+				value, err = entry.FloatField("p90")
+				So(err, ShouldBeNil)
+				So(value, ShouldEqual, 4)
+
+				valuestr, err := entry.StringField("stddev")
+				So(err, ShouldBeNil)
+				So(valuestr, ShouldEqual, "2.45")
+
+				entry, err = result.EntryField("bar")
+				So(err, ShouldBeNil)
+
+				value, err = entry.FloatField("mean")
 				So(err, ShouldBeNil)
-				So(valuestr, ShouldEqual, `{"mean":"4.00","p10":"0.00","p5":"0.00","p50":"1.00","p90":"4.00","p95":"4.00","p99":"4.00","stddev":"2.45","total":"3.00"}`)
+				So(value, ShouldEqual, 5)
 
-				valuestr, err = result.Field("bar")
+				value, err = entry.FloatField("p50")
 				So(err, ShouldBeNil)
-				So(valuestr, ShouldEqual, `{"mean":"5.00","p10":"0.00","p5":"0.00","p50":"2.00","p90":"5.00","p95":"5.00","p99":"5.00","stddev":"2.45","total":"3.00"}`)
+				So(value, ShouldEqual, 2)
 			})
 
 		})
 	})
 }
+
+func TestQuantileReducer(t *testing.T) {
+	Convey("Test quantiles out of range", t, func() {
+		var errs []error
+		ErrorHandler = func(err error) { errs = append(errs, err) }
+		defer func() { ErrorHandler = nil }()
+
+		input := make(chan *Entry, 10)
+		for _, value := range []string{"-1", "0", "-2"} {
+			input <- NewEntry(Fields{"foo": value})
+		}
+		close(input)
+		output := make(chan *Entry, 10)
+
+		reducer := &Quantile{Fields: map[string]string{"foo": "foo"}, Quantiles: []float64{0.5, 2}}
+		reducer.Reduce(input, output)
+		result, err := (<-output).EntryField("foo")
+		So(err, ShouldBeNil)
+		So(errs, ShouldHaveLength, 1)
+		_, err = result.FloatField("p50")
+		So(err, ShouldBeNil)
+		_, err = result.FloatField("p200")
+		So(err, ShouldNotBeNil)
+
+		// Reported once however many groups there are
+		errs = nil
+		input = make(chan *Entry, 10)
+		for _, value := range []string{"a", "b", "c"} {
+			input <- NewEntry(Fields{"foo": "1", "group": value})
+		}
+		close(input)
+		output = make(chan *Entry, 10)
+		reducer = &Quantile{Fields: map[string]string{"foo": "foo"}, Quantiles: []float64{0.5, 2}}
+		NewGroupBy([]string{"group"}, reducer).Reduce(input, output)
+		So(output, ShouldHaveLength, 3)
+		So(errs, ShouldHaveLength, 1)
+
+		// No positive values to take the maximum from
+		s := newSketch()
+		s.add(-1, 1.02)
+		So(s.quantile(2, 1.02), ShouldBeLessThan, 0)
+	})
+}
@@ -0,0 +1,196 @@
+package gonx
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Default names of fields Window reducer tags results with.
+const (
+	DefaultWindowStartField = "window_start"
+	DefaultWindowEndField   = "window_end"
+)
+
+// Window implements the Reducer interface to calculate Reducer results for each
+// Period of time, e.g. per minute or per day statistics of a followed log.
+//
+// Entries are assigned to windows by the timestamp in Field, parsed with Format
+// or by ParseTimeInLocation if it is empty. Window results are sent as soon as
+// an entry later than the window end plus Lateness arrives, so entries coming
+// slightly out of order are accounted. With Live set, windows are also closed by
+// the wall clock, so results come out when the log is idle. Remaining windows
+// are sent when the input is closed. Entries of closed windows are dropped and
+// reported to ErrorHandler.
+//
+// Each result is tagged with the window bounds in DefaultWindowStartField and
+// DefaultWindowEndField fields formatted as RFC 3339 in the Location.
+type Window struct {
+	Field    string
+	Format   string
+	Location *time.Location
+	Period   time.Duration
+	Lateness time.Duration
+	Live     bool
+	Reducer  Reducer
+}
+
+// NewWindow creates a Window reducer for the timestamp field.
+func NewWindow(field string, period time.Duration, reducer Reducer) *Window {
+	return &Window{Field: field, Period: period, Reducer: reducer}
+}
+
+// window is a single period aggregation, synchronous if the reducer is an
+// Aggregator, otherwise the reducer runs in a separate goroutine.
+type window struct {
+	start  time.Time
+	acc    Accumulator
+	input  chan *Entry
+	output chan *Entry
+}
+
+func (w *Window) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+func (w *Window) parse(entry *Entry) (time.Time, error) {
+	val, err := entry.StringField(w.Field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if w.Format == "" {
+		return ParseTimeInLocation(val, w.location())
+	}
+	return time.ParseInLocation(w.Format, val, w.location())
+}
+
+// windowStart truncates the time to the period start in the location, so e.g.
+// daily windows start at the local midnight.
+func (w *Window) windowStart(t time.Time) time.Time {
+	_, offset := t.In(w.location()).Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(w.Period).Add(-shift).In(w.location())
+}
+
+func (w *Window) open(start time.Time) *window {
+	win := &window{start: start}
+	if aggregator, ok := w.Reducer.(Aggregator); ok {
+		win.acc = aggregator.Init()
+	}
+	if win.acc == nil {
+		win.input = make(chan *Entry, 10)
+		win.output = make(chan *Entry, 10)
+		go w.Reducer.Reduce(win.input, win.output)
+	}
+	return win
+}
+
+func (win *window) add(entry *Entry) {
+	if win.acc != nil {
+		win.acc.Add(entry)
+	} else {
+		win.input <- entry
+	}
+}
+
+// results returns the window reducer results.
+func (win *window) results() []*Entry {
+	if win.acc != nil {
+		return []*Entry{win.acc.Result()}
+	}
+	close(win.input)
+	var results []*Entry
+	for entry := range win.output {
+		results = append(results, entry)
+	}
+	return results
+}
+
+// Reduce calculates the reducer results for each window of the input entries.
+// A non-positive Period is reported to ErrorHandler and no results are sent.
+func (w *Window) Reduce(input chan *Entry, output chan *Entry) {
+	if w.Period <= 0 {
+		handleError(fmt.Errorf("window period %v is not positive", w.Period))
+		close(output)
+		for range input {
+		}
+		return
+	}
+
+	windows := make(map[int64]*window)
+	var watermark, closed time.Time
+
+	// flush sends results of windows closed by the time, all if it is zero
+	flush := func(until time.Time) {
+		var starts []int64
+		for key, win := range windows {
+			if until.IsZero() || !win.start.Add(w.Period+w.Lateness).After(until) {
+				starts = append(starts, key)
+			}
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+		for _, key := range starts {
+			win := windows[key]
+			delete(windows, key)
+			end := win.start.Add(w.Period)
+			for _, result := range win.results() {
+				entry := NewEmptyEntry()
+				entry.Merge(result)
+				entry.SetField(DefaultWindowStartField, win.start.Format(time.RFC3339))
+				entry.SetField(DefaultWindowEndField, end.Format(time.RFC3339))
+				output <- entry
+			}
+			if end.After(closed) {
+				closed = end
+			}
+		}
+	}
+
+	var tick <-chan time.Time
+	if w.Live {
+		interval := w.Period
+		if interval > time.Second {
+			interval = time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case entry, ok := <-input:
+			if !ok {
+				flush(time.Time{})
+				close(output)
+				return
+			}
+			t, err := w.parse(entry)
+			if err != nil {
+				handleError(err)
+				continue
+			}
+			start := w.windowStart(t)
+			if start.Before(closed) {
+				handleError(fmt.Errorf("entry at %v is too late, window is closed", t))
+				continue
+			}
+			win, ok := windows[start.UnixNano()]
+			if !ok {
+				win = w.open(start)
+				windows[start.UnixNano()] = win
+			}
+			win.add(entry)
+			if t.After(watermark) {
+				watermark = t
+				flush(watermark)
+			}
+
+		case now := <-tick:
+			flush(now)
+		}
+	}
+}
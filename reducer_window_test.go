@@ -0,0 +1,112 @@
+package gonx
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWindow(t *testing.T) {
+	Convey("Test Window reducer", t, func() {
+		var errs []error
+		ErrorHandler = func(err error) { errs = append(errs, err) }
+		defer func() { ErrorHandler = nil }()
+
+		entry := func(ts string, bytes string) *Entry {
+			return NewEntry(Fields{"time": ts, "bytes": bytes})
+		}
+		run := func(w *Window, entries ...*Entry) []*Entry {
+			input := make(chan *Entry, len(entries))
+			output := make(chan *Entry, 10)
+			for _, e := range entries {
+				input <- e
+			}
+			close(input)
+			w.Reduce(input, output)
+			var results []*Entry
+			for result := range output {
+				results = append(results, result)
+			}
+			return results
+		}
+
+		Convey("Aggregator per window", func() {
+			w := NewWindow("time", time.Minute, NewChain(&Count{}, &Sum{map[string]string{"bytes": "bytes"}}))
+			w.Location = time.UTC
+			w.Lateness = 10 * time.Second
+			results := run(w,
+				entry("2024-01-01T10:00:05Z", "1"),
+				entry("2024-01-01T10:00:50Z", "2"),
+				entry("2024-01-01T10:01:05Z", "4"),
+				// late but within lateness
+				entry("2024-01-01T10:00:59Z", "8"),
+				entry("2024-01-01T10:01:30Z", "16"),
+				// first window is closed
+				entry("2024-01-01T10:00:01Z", "32"),
+				entry("2024-01-01T10:02:00Z", "64"),
+			)
+			So(results, ShouldHaveLength, 3)
+
+			start, _ := results[0].StringField(DefaultWindowStartField)
+			end, _ := results[0].StringField(DefaultWindowEndField)
+			count, _ := results[0].FloatField("count")
+			sum, _ := results[0].FloatField("bytes")
+			So(start, ShouldEqual, "2024-01-01T10:00:00Z")
+			So(end, ShouldEqual, "2024-01-01T10:01:00Z")
+			So(count, ShouldEqual, 3)
+			So(sum, ShouldEqual, 11)
+
+			sum, _ = results[1].FloatField("bytes")
+			So(sum, ShouldEqual, 20)
+			sum, _ = results[2].FloatField("bytes")
+			So(sum, ShouldEqual, 64)
+			So(errs, ShouldHaveLength, 1)
+		})
+
+		Convey("Reject non-positive period", func() {
+			w := NewWindow("time", 0, &Count{})
+			w.Live = true
+			results := run(w, entry("2024-01-01T10:00:05Z", "1"))
+			So(results, ShouldBeEmpty)
+			So(errs, ShouldHaveLength, 1)
+		})
+
+		Convey("Daily windows in location", func() {
+			loc := time.FixedZone("UTC+3", 3*3600)
+			w := NewWindow("time", 24*time.Hour, NewGroupBy([]string{"bytes"}, &Count{}))
+			w.Format = TimeLocalLayout
+			w.Location = loc
+			results := run(w,
+				entry("01/Jan/2024:23:59:59 +0300", "1"),
+				entry("02/Jan/2024:00:00:01 +0300", "1"),
+				entry("02/Jan/2024:12:00:00 +0300", "2"),
+			)
+			So(results, ShouldHaveLength, 3)
+			start, _ := results[1].StringField(DefaultWindowStartField)
+			So(start, ShouldEqual, "2024-01-02T00:00:00+03:00")
+			bytes, _ := results[2].StringField("bytes")
+			So(bytes, ShouldEqual, "2")
+		})
+
+		Convey("Live windows are closed by wall clock", func() {
+			w := NewWindow("time", 100*time.Millisecond, &Count{})
+			w.Live = true
+			input := make(chan *Entry, 1)
+			output := make(chan *Entry, 1)
+			go w.Reduce(input, output)
+			input <- entry(time.Now().Format(time.RFC3339Nano), "1")
+
+			select {
+			case result := <-output:
+				count, _ := result.FloatField("count")
+				So(count, ShouldEqual, 1)
+			case <-time.After(5 * time.Second):
+				So("timeout", ShouldBeEmpty)
+			}
+			close(input)
+			_, ok := <-output
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
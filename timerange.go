@@ -0,0 +1,201 @@
+package gonx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TimeLocalLayout is the layout of nginx $time_local variable.
+const TimeLocalLayout = "02/Jan/2006:15:04:05 -0700"
+
+// TimeLayouts are common log timestamp layouts tried by ParseTime: nginx
+// $time_local, $time_iso8601, error log timestamp and the common date time.
+var TimeLayouts = []string{
+	TimeLocalLayout,
+	time.RFC3339Nano,
+	ErrorLogTimeLayout,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseTime parses a timestamp trying each of TimeLayouts. Timestamps without
+// a timezone are parsed in UTC.
+func ParseTime(value string) (time.Time, error) {
+	return ParseTimeInLocation(value, time.UTC)
+}
+
+// ParseTimeInLocation is like ParseTime but timestamps without a timezone are
+// parsed in the given location.
+func ParseTimeInLocation(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range TimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse '%v' as time", value)
+}
+
+// probeLines is the max number of lines read to find a valid timestamp at some
+// file position during the binary search.
+const probeLines = 100
+
+// TimeRange configures TimeRangeReader. Start is inclusive and End is exclusive,
+// zero values leave the range open. Entries in the log could be out of time order
+// not more than by Tolerance. Format defaults to TimeLocalLayout.
+type TimeRange struct {
+	Field     string
+	Format    string
+	Start     time.Time
+	End       time.Time
+	Tolerance time.Duration
+}
+
+// TimeRangeReader reads entries within a time range from a log file written in
+// time order. Instead of scanning the whole file it uses binary search to jump
+// to the start of the range and stops reading after the end of it.
+type TimeRangeReader struct {
+	file   io.ReaderAt
+	size   int64
+	parser StringParser
+	config TimeRange
+	reader *bufio.Reader
+	done   bool
+}
+
+// NewTimeRangeReader creates a reader of file with given size, e.g. an *os.File.
+func NewTimeRangeReader(file io.ReaderAt, size int64, parser StringParser, config TimeRange) *TimeRangeReader {
+	if config.Format == "" {
+		config.Format = TimeLocalLayout
+	}
+	return &TimeRangeReader{
+		file:   file,
+		size:   size,
+		parser: parser,
+		config: config,
+	}
+}
+
+// Read next parsed Entry within the time range. Return EOF if there are no
+// Entries to read. Lines which could not be parsed are skipped.
+func (r *TimeRangeReader) Read() (*Entry, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	if r.reader == nil {
+		offset, err := r.search()
+		if err != nil {
+			return nil, err
+		}
+		r.reader = r.readerAt(offset)
+	}
+	for {
+		line, err := readLine(r.reader)
+		if err != nil {
+			return nil, err
+		}
+		entry, t, ok := r.parse(line)
+		if !ok {
+			continue
+		}
+		if !r.config.End.IsZero() && !t.Before(r.config.End.Add(r.config.Tolerance)) {
+			r.done = true
+			return nil, io.EOF
+		}
+		if !t.Before(r.config.Start) && (r.config.End.IsZero() || t.Before(r.config.End)) {
+			return entry, nil
+		}
+	}
+}
+
+// parse returns the parsed entry and its timestamp or false if the line is invalid.
+func (r *TimeRangeReader) parse(line string) (*Entry, time.Time, bool) {
+	entry, err := r.parser.ParseString(line)
+	if err != nil {
+		handleError(err)
+		return nil, time.Time{}, false
+	}
+	if entry == nil {
+		// Dropped by MapperParser
+		return nil, time.Time{}, false
+	}
+	val, err := entry.StringField(r.config.Field)
+	if err != nil {
+		handleError(err)
+		return nil, time.Time{}, false
+	}
+	t, err := time.Parse(r.config.Format, val)
+	if err != nil {
+		handleError(err)
+		return nil, time.Time{}, false
+	}
+	return entry, t, true
+}
+
+func (r *TimeRangeReader) readerAt(offset int64) *bufio.Reader {
+	return bufio.NewReader(io.NewSectionReader(r.file, offset, r.size-offset))
+}
+
+// search returns the offset of the first line with timestamp not before the
+// range start minus tolerance.
+func (r *TimeRangeReader) search() (int64, error) {
+	if r.config.Start.IsZero() {
+		return 0, nil
+	}
+	target := r.config.Start.Add(-r.config.Tolerance)
+	lo, hi := int64(0), r.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		_, t, ok, err := r.probe(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok && t.Before(target) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	start, _, _, err := r.probe(lo)
+	return start, err
+}
+
+// probe finds the first line starting at offset or after it and returns its
+// start and the timestamp of the first valid line from there. It returns false
+// if no valid line is found.
+func (r *TimeRangeReader) probe(offset int64) (int64, time.Time, bool, error) {
+	start := offset
+	if offset > 0 {
+		// The line starts at offset only if it follows a line delimiter
+		start = offset - 1
+	}
+	reader := r.readerAt(start)
+	if offset > 0 {
+		skipped, err := reader.ReadSlice('\n')
+		for err == bufio.ErrBufferFull {
+			start += int64(len(skipped))
+			skipped, err = reader.ReadSlice('\n')
+		}
+		start += int64(len(skipped))
+		if err == io.EOF {
+			return start, time.Time{}, false, nil
+		}
+		if err != nil {
+			return 0, time.Time{}, false, err
+		}
+	}
+	for i := 0; i < probeLines; i++ {
+		line, err := readLine(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, time.Time{}, false, err
+		}
+		if _, t, ok := r.parse(line); ok {
+			return start, t, true, nil
+		}
+	}
+	return start, time.Time{}, false, nil
+}
@@ -0,0 +1,78 @@
+package gonx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTimeRangeReader(t *testing.T) {
+	Convey("Test time range reader", t, func() {
+		base := time.Date(2013, time.November, 8, 13, 0, 0, 0, time.UTC)
+		var lines []string
+		for i := 0; i < 1000; i++ {
+			ts := base.Add(time.Duration(i) * time.Second)
+			// Every 10th line is a bit late
+			if i%10 == 5 {
+				ts = ts.Add(-3 * time.Second)
+			}
+			lines = append(lines, fmt.Sprintf("%d [%s] \"GET / HTTP/1.1\"", i, ts.Format(TimeLocalLayout)))
+			if i%100 == 50 {
+				lines = append(lines, "garbage line")
+			}
+		}
+		log := strings.Join(lines, "\n") + "\n"
+		parser := NewParser(`$id [$time_local] "$request"`)
+
+		readIDs := func(config TimeRange) (ids []string) {
+			config.Field = "time_local"
+			reader := NewTimeRangeReader(strings.NewReader(log), int64(len(log)), parser, config)
+			for {
+				entry, err := reader.Read()
+				if err == io.EOF {
+					return
+				}
+				So(err, ShouldBeNil)
+				id, _ := entry.StringField("id")
+				ids = append(ids, id)
+			}
+		}
+
+		Convey("Read closed range", func() {
+			ids := readIDs(TimeRange{
+				Start:     base.Add(500 * time.Second),
+				End:       base.Add(510 * time.Second),
+				Tolerance: 5 * time.Second,
+			})
+			So(ids, ShouldResemble, []string{"500", "501", "502", "503", "504", "505", "506", "507", "508", "509"})
+		})
+
+		Convey("Read late lines within tolerance", func() {
+			config := TimeRange{
+				Start: base.Add(510 * time.Second),
+				End:   base.Add(513 * time.Second),
+			}
+			So(readIDs(config), ShouldResemble, []string{"510", "511", "512"})
+
+			config.Tolerance = 5 * time.Second
+			So(readIDs(config), ShouldResemble, []string{"510", "511", "512", "515"})
+		})
+
+		Convey("Read open ended range", func() {
+			ids := readIDs(TimeRange{Start: base.Add(997 * time.Second)})
+			So(ids, ShouldResemble, []string{"997", "998", "999"})
+
+			ids = readIDs(TimeRange{End: base.Add(2 * time.Second)})
+			So(ids, ShouldResemble, []string{"0", "1"})
+		})
+
+		Convey("Read range out of the log", func() {
+			So(readIDs(TimeRange{Start: base.Add(time.Hour)}), ShouldBeEmpty)
+			So(readIDs(TimeRange{End: base.Add(-time.Hour)}), ShouldBeEmpty)
+		})
+	})
+}